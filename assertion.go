@@ -0,0 +1,147 @@
+package health
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// BodyAssertion is a check against a response body. Exactly one of `contains`,
+// `notContains`, `matches` or `jsonPath` should be set; a `jsonPath` is
+// combined with one of `exists`, `equals`, `lessThan` or `greaterThan`.
+type BodyAssertion struct {
+	Contains    string      `json:"contains" yaml:"contains"`
+	NotContains string      `json:"not_contains" yaml:"notContains"`
+	Matches     string      `json:"matches" yaml:"matches"`
+	JSONPath    string      `json:"json_path" yaml:"jsonPath"`
+	Exists      *bool       `json:"exists" yaml:"exists"`
+	Equals      interface{} `json:"equals" yaml:"equals"`
+	LessThan    *float64    `json:"less_than" yaml:"lessThan"`
+	GreaterThan *float64    `json:"greater_than" yaml:"greaterThan"`
+
+	// matches is `Matches` compiled by `CompileBodyAssertions`.
+	matches *regexp.Regexp
+}
+
+// Validate returns an error if the assertion is malformed.
+func (ba BodyAssertion) Validate() error {
+	if len(ba.JSONPath) == 0 && (ba.Exists != nil || ba.Equals != nil || ba.LessThan != nil || ba.GreaterThan != nil) {
+		return fmt.Errorf("body assertion `exists`, `equals`, `lessThan` and `greaterThan` require a `jsonPath`")
+	}
+	if len(ba.Matches) > 0 {
+		if _, err := regexp.Compile(ba.Matches); err != nil {
+			return fmt.Errorf("invalid body assertion regex %q: %v", ba.Matches, err)
+		}
+		return nil
+	}
+	if len(ba.Contains) == 0 && len(ba.NotContains) == 0 && len(ba.JSONPath) == 0 {
+		return fmt.Errorf("body assertion must set one of `contains`, `notContains`, `matches` or `jsonPath`")
+	}
+	return nil
+}
+
+// CompileBodyAssertions validates a list of assertions and returns a copy of
+// them with their regexes compiled, so they aren't compiled on every check.
+func CompileBodyAssertions(assertions []BodyAssertion) ([]BodyAssertion, error) {
+	var compiled []BodyAssertion
+	for _, assertion := range assertions {
+		if err := assertion.Validate(); err != nil {
+			return nil, err
+		}
+		if len(assertion.Matches) > 0 {
+			assertion.matches = regexp.MustCompile(assertion.Matches)
+		}
+		compiled = append(compiled, assertion)
+	}
+	return compiled, nil
+}
+
+// Check runs the assertion against a body. `document` is the body parsed as
+// json, and is only required for `jsonPath` assertions.
+func (ba BodyAssertion) Check(body []byte, document interface{}) error {
+	if len(ba.Contains) > 0 && !strings.Contains(string(body), ba.Contains) {
+		return fmt.Errorf("expected body to contain %q", ba.Contains)
+	}
+	if len(ba.NotContains) > 0 && strings.Contains(string(body), ba.NotContains) {
+		return fmt.Errorf("expected body not to contain %q", ba.NotContains)
+	}
+	if len(ba.Matches) > 0 {
+		pattern := ba.matches
+		if pattern == nil {
+			var err error
+			if pattern, err = regexp.Compile(ba.Matches); err != nil {
+				return err
+			}
+		}
+		if !pattern.Match(body) {
+			return fmt.Errorf("expected body to match %q", ba.Matches)
+		}
+	}
+	if len(ba.JSONPath) > 0 {
+		return ba.checkJSONPath(document)
+	}
+	return nil
+}
+
+func (ba BodyAssertion) checkJSONPath(document interface{}) error {
+	value, exists := JSONPath(document, ba.JSONPath)
+	if ba.Exists != nil {
+		if *ba.Exists && !exists {
+			return fmt.Errorf("expected json path %q to exist", ba.JSONPath)
+		}
+		if !*ba.Exists && exists {
+			return fmt.Errorf("expected json path %q not to exist", ba.JSONPath)
+		}
+	}
+
+	if ba.Equals == nil && ba.LessThan == nil && ba.GreaterThan == nil {
+		if ba.Exists == nil && !exists {
+			return fmt.Errorf("expected json path %q to exist", ba.JSONPath)
+		}
+		return nil
+	}
+	if !exists {
+		return fmt.Errorf("json path %q not found", ba.JSONPath)
+	}
+
+	actual := FormatJSONValue(value)
+	if ba.Equals != nil {
+		if expected := fmt.Sprint(ba.Equals); actual != expected {
+			return fmt.Errorf("expected json path %q to equal %q, got %q", ba.JSONPath, expected, actual)
+		}
+	}
+
+	if ba.LessThan == nil && ba.GreaterThan == nil {
+		return nil
+	}
+	number, err := strconv.ParseFloat(actual, 64)
+	if err != nil {
+		return fmt.Errorf("expected json path %q to be a number, got %q", ba.JSONPath, actual)
+	}
+	if ba.LessThan != nil && !(number < *ba.LessThan) {
+		return fmt.Errorf("expected json path %q to be less than %v, got %v", ba.JSONPath, *ba.LessThan, number)
+	}
+	if ba.GreaterThan != nil && !(number > *ba.GreaterThan) {
+		return fmt.Errorf("expected json path %q to be greater than %v, got %v", ba.JSONPath, *ba.GreaterThan, number)
+	}
+	return nil
+}
+
+// CheckBodyAssertions runs a list of assertions against a body and returns the first failure.
+func CheckBodyAssertions(assertions []BodyAssertion, body []byte) error {
+	var document interface{}
+	for _, assertion := range assertions {
+		if len(assertion.JSONPath) > 0 && document == nil {
+			parsed, err := ParseJSON(body)
+			if err != nil {
+				return fmt.Errorf("body assertion failed: %v", err)
+			}
+			document = parsed
+		}
+		if err := assertion.Check(body, document); err != nil {
+			return fmt.Errorf("body assertion failed: %v", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package health
+
+import (
+	"testing"
+
+	"github.com/blendlabs/go-assert"
+)
+
+func TestCheckBodyAssertions(t *testing.T) {
+	assert := assert.New(t)
+
+	body := []byte(`{"status":"degraded","queue":{"depth":1500},"version":"1.2.3"}`)
+	yes, no := true, false
+	limit := 1000.0
+
+	assert.Nil(CheckBodyAssertions([]BodyAssertion{{Contains: "queue"}}, body))
+	assert.NotNil(CheckBodyAssertions([]BodyAssertion{{NotContains: "degraded"}}, body))
+	assert.Nil(CheckBodyAssertions([]BodyAssertion{{Matches: `"version":"\d+\.\d+\.\d+"`}}, body))
+	assert.Nil(CheckBodyAssertions([]BodyAssertion{{JSONPath: "queue.depth", Exists: &yes}}, body))
+	assert.NotNil(CheckBodyAssertions([]BodyAssertion{{JSONPath: "queue.depth", Exists: &no}}, body))
+	assert.NotNil(CheckBodyAssertions([]BodyAssertion{{JSONPath: "$.status", Equals: "ok"}}, body))
+	assert.Nil(CheckBodyAssertions([]BodyAssertion{{JSONPath: "queue.depth", Equals: 1500}}, body))
+	assert.NotNil(CheckBodyAssertions([]BodyAssertion{{JSONPath: "queue.depth", LessThan: &limit}}, body))
+	assert.Nil(CheckBodyAssertions([]BodyAssertion{{JSONPath: "queue.depth", GreaterThan: &limit}}, body))
+	assert.NotNil(CheckBodyAssertions([]BodyAssertion{{JSONPath: "status", Equals: "ok"}}, []byte("not json")))
+}
+
+func TestBodyAssertionValidate(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Nil(BodyAssertion{Contains: "ok"}.Validate())
+	assert.NotNil(BodyAssertion{Matches: "("}.Validate())
+	assert.NotNil(BodyAssertion{}.Validate())
+
+	yes := true
+	limit := 1000.0
+	assert.Nil(BodyAssertion{JSONPath: "queue.depth", LessThan: &limit}.Validate())
+	assert.NotNil(BodyAssertion{Equals: "ok"}.Validate())
+	assert.NotNil(BodyAssertion{Contains: "ok", Exists: &yes}.Validate())
+	assert.NotNil(BodyAssertion{LessThan: &limit}.Validate())
+	assert.NotNil(BodyAssertion{Matches: "ok", GreaterThan: &limit}.Validate())
+}
+
+func TestCompileBodyAssertions(t *testing.T) {
+	assert := assert.New(t)
+
+	assertions := []BodyAssertion{{Contains: "ok"}, {Matches: `\d+`}}
+	compiled, err := CompileBodyAssertions(assertions)
+	assert.Nil(err)
+	assert.Len(compiled, 2)
+	assert.NotNil(compiled[1].matches)
+	assert.Nil(assertions[1].matches, "the assertions passed in should be left alone")
+	assert.Nil(CheckBodyAssertions(compiled, []byte("ok, build 42")))
+	assert.NotNil(CheckBodyAssertions(compiled, []byte("ok")))
+
+	_, err = CompileBodyAssertions([]BodyAssertion{{Matches: "("}})
+	assert.NotNil(err)
+}
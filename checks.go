@@ -3,6 +3,8 @@ package health
 import (
 	"fmt"
 	"io"
+	"net"
+	"net/http"
 	"sync"
 	"time"
 
@@ -15,25 +17,61 @@ type CheckIntervalAction func(c *Checks)
 // NewChecksFromConfig initializes a check set from a config.
 func NewChecksFromConfig(config *Config) (*Checks, error) {
 	c := &Checks{
-		config:  config,
-		abort:   make(chan bool),
-		aborted: make(chan bool),
+		config:     config,
+		heartbeats: NewHeartbeats(),
+		abort:      make(chan bool),
+		aborted:    make(chan bool),
 	}
+	c.heartbeats.Token = config.HeartbeatToken
+	groups := map[string]GroupConfig{}
+	for _, group := range config.Groups {
+		groups[group.Name] = group
+	}
+
 	var longestHost int
-	for _, h := range config.Hosts {
-		host, err := NewHost(h, config.PingTimeout, config.MaxStats)
+	for index := range config.Hosts {
+		// copied so a version inherited from the host's group isn't written back to the config.
+		h := config.Hosts[index]
+		if len(h.Group) > 0 {
+			group, hasGroup := groups[h.Group]
+			if !hasGroup {
+				return nil, fmt.Errorf("host %s is in an unknown group: %q", h.URL, h.Group)
+			}
+			if h.Version == nil {
+				h.Version = group.Version
+			}
+		}
+		h.heartbeats = c.heartbeats
+		host, err := NewHostFromConfig(&h, config.PingTimeout, config.MaxStats)
 		if err != nil {
 			return nil, err
 		}
+		if len(h.Group) > 0 {
+			if versioned, isVersioned := host.prober.(VersionProber); !isVersioned || !versioned.ReportsVersion() {
+				return nil, fmt.Errorf("host %s is in group %q but can't report a version; only http hosts with a `version` on the host or group can be compared", host.Name(), h.Group)
+			}
+		}
+		if heartbeat, isHeartbeat := host.prober.(*HeartbeatProber); isHeartbeat && len(heartbeat.stateFile) == 0 && len(config.Listen) == 0 {
+			return nil, fmt.Errorf("host %s can never receive a heartbeat; set `listen` or give the host a `stateFile`", host.Name())
+		}
 		c.hosts = append(
 			c.hosts,
 			host,
 		)
-		if len(h) > longestHost {
-			longestHost = len(h)
+		if len(host.Name()) > longestHost {
+			longestHost = len(host.Name())
 		}
 	}
 	c.longestHost = longestHost
+
+	if len(config.Listen) > 0 {
+		listener, err := net.Listen("tcp", config.Listen)
+		if err != nil {
+			return nil, err
+		}
+		c.listener = listener
+		c.server = &http.Server{Handler: c.Handler()}
+	}
 	return c, nil
 }
 
@@ -46,10 +84,16 @@ type Checks struct {
 	aborted        chan bool
 	intervalAction CheckIntervalAction
 	longestHost    int
+	listener       net.Listener
+	server         *http.Server
+	heartbeats     *Heartbeats
+
+	serveLock sync.Mutex
+	serveErr  error
 }
 
 // Hosts returns the hosts for the checks collection.
-func (c Checks) Hosts() []*Host {
+func (c *Checks) Hosts() []*Host {
 	return c.hosts
 }
 
@@ -58,9 +102,44 @@ func (c *Checks) OnInterval(action CheckIntervalAction) {
 	c.intervalAction = action
 }
 
+// Heartbeats returns the heartbeat store for the checks' `heartbeat://` hosts.
+func (c *Checks) Heartbeats() *Heartbeats {
+	return c.heartbeats
+}
+
+// Handler returns the http handler for the endpoints `health` serves when
+// `listen` is set, which accepts heartbeats at `/heartbeat/<name>`.
+func (c *Checks) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle(HeartbeatPath, c.heartbeats)
+	return mux
+}
+
+// ServeErr returns the error the heartbeat listener stopped with, if it has.
+func (c *Checks) ServeErr() error {
+	c.serveLock.Lock()
+	defer c.serveLock.Unlock()
+	return c.serveErr
+}
+
+// serve serves the heartbeat listener, recording the error it stops with
+// so it can be shown with the statuses.
+func (c *Checks) serve() {
+	err := c.server.Serve(c.listener)
+	if err == http.ErrServerClosed {
+		return
+	}
+	c.serveLock.Lock()
+	defer c.serveLock.Unlock()
+	c.serveErr = err
+}
+
 // Start starts the healthcheck
 func (c *Checks) Start() {
 	c.startedAtUTC = time.Now().UTC()
+	if c.server != nil {
+		go c.serve()
+	}
 	pingTicker := time.NewTicker(c.config.PollInterval)
 	refreshTicker := time.NewTicker(c.config.RefreshInterval)
 
@@ -83,6 +162,9 @@ func (c *Checks) Start() {
 func (c *Checks) Stop() {
 	c.abort <- true
 	<-c.aborted
+	if c.server != nil {
+		c.server.Close()
+	}
 }
 
 // PingAll pings all the hosts.
@@ -93,31 +175,69 @@ func (c *Checks) PingAll() {
 		go func(x int) {
 			defer wg.Done()
 			host := c.hosts[x]
+			if err := host.Resolve(time.Now()); err != nil {
+				host.errs.Enqueue(err)
+			}
+
+			children := sync.WaitGroup{}
+			children.Add(len(host.children))
+			for _, child := range host.children {
+				go func(child *Host) {
+					defer children.Done()
+					if err := c.Ping(child); err != nil {
+						child.errs.Enqueue(err)
+					}
+				}(child)
+			}
 			err := c.Ping(host)
 			if err != nil {
 				host.errs.Enqueue(err)
 			}
+			children.Wait()
 		}(index)
 	}
 	wg.Wait()
+
+	for _, host := range c.allHosts() {
+		if len(host.statusName()) > c.longestHost {
+			c.longestHost = len(host.statusName())
+		}
+	}
+}
+
+// allHosts returns the hosts with the per ip children of `ResolveAll` hosts
+// following their parents.
+func (c *Checks) allHosts() []*Host {
+	var hosts []*Host
+	for _, host := range c.hosts {
+		hosts = append(hosts, host)
+		hosts = append(hosts, host.children...)
+	}
+	return hosts
 }
 
-// Ping performs a ping and marks the host up or down.
+// Ping performs a ping and marks the host up or down. Only `StatusDown` marks
+// the host down; `StatusWarn` and `StatusUnknown` count as up for its uptime.
 func (c *Checks) Ping(h *Host) error {
-	elapsed, err := h.Ping()
-	if err != nil {
+	result := h.Probe()
+	if result.Status == StatusDown {
 		h.SetDown(time.Now())
 	} else {
 		h.SetUp()
 	}
-	h.AddTiming(elapsed)
-	return err
+	h.status = result.Status
+	h.details = result.Details
+	h.metrics = result.Metrics
+	h.version = result.Version
+	h.AddTiming(result.Elapsed)
+	h.AddPhaseTimings(result.Timings)
+	return result.Err
 }
 
 // HasErrors returns if the checks collection has a host with errors.
 func (c *Checks) HasErrors() bool {
-	for index := range c.hosts {
-		if c.hosts[index].errs.Len() > 0 {
+	for _, host := range c.allHosts() {
+		if host.errs.Len() > 0 {
 			return true
 		}
 	}
@@ -127,8 +247,8 @@ func (c *Checks) HasErrors() bool {
 // MaxElapsed returns the maximum elapsed for the entire checks list.
 func (c *Checks) MaxElapsed() time.Duration {
 	var elapsed time.Duration
-	for index := range c.hosts {
-		c.hosts[index].stats.Each(func(v interface{}) {
+	for _, host := range c.allHosts() {
+		host.stats.Each(func(v interface{}) {
 			if typed, isTyped := v.(time.Duration); isTyped {
 				if typed > elapsed {
 					elapsed = typed
@@ -142,13 +262,34 @@ func (c *Checks) MaxElapsed() time.Duration {
 // WriteStatus writes the statuses for all the hosts.
 func (c *Checks) WriteStatus(writer io.Writer) error {
 	fmt.Fprintf(writer, "%s :: running for: %v, refresh: %v, poll: %v, timeout: %v\r\n", util.ColorLightWhite.Apply("Health"), time.Now().UTC().Sub(c.startedAtUTC), c.config.RefreshInterval, c.config.PollInterval, c.config.PingTimeout)
+	if serveErr := c.ServeErr(); serveErr != nil {
+		fmt.Fprintf(writer, "%s\r\n", util.ColorRed.Apply(fmt.Sprintf("not receiving heartbeats, the listener on %s stopped: %v", c.config.Listen, serveErr)))
+	}
 	var err error
 	maxElapsed := c.MaxElapsed()
-	for index := range c.hosts {
-		err = c.hosts[index].WriteStatus(c.longestHost, maxElapsed, writer)
+	hosts := c.allHosts()
+	for _, host := range hosts {
+		err = host.WriteStatus(c.longestHost, maxElapsed, writer)
 		if err != nil {
 			return err
 		}
+		if c.config.Verbose {
+			err = host.WriteTimingStatus(c.longestHost, writer)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if drift := FindVersionDrift(hosts); len(drift) > 0 {
+		fmt.Fprintf(writer, "\r\n")
+		fmt.Fprintf(writer, "%s\r\n", util.ColorYellow.Apply("Version Drift:"))
+		for _, group := range drift {
+			err = WriteVersionDrift(group, c.longestHost, writer)
+			if err != nil {
+				return err
+			}
+		}
 	}
 
 	if !c.HasErrors() {
@@ -158,8 +299,8 @@ func (c *Checks) WriteStatus(writer io.Writer) error {
 	fmt.Fprintf(writer, "\r\n")
 	fmt.Fprintf(writer, "%s\r\n", util.ColorYellow.Apply("Downtime:"))
 
-	for index := range c.hosts {
-		err = c.hosts[index].WriteDowntimeStatus(c.longestHost, writer)
+	for _, host := range hosts {
+		err = host.WriteDowntimeStatus(c.longestHost, writer)
 		if err != nil {
 			return err
 		}
@@ -168,8 +309,8 @@ func (c *Checks) WriteStatus(writer io.Writer) error {
 	fmt.Fprintf(writer, "\r\n")
 	fmt.Fprintf(writer, "%s\r\n", util.ColorRed.Apply("Errors:"))
 
-	for index := range c.hosts {
-		err = c.hosts[index].WriteErrorStatus(c.longestHost, writer)
+	for _, host := range hosts {
+		err = host.WriteErrorStatus(c.longestHost, writer)
 		if err != nil {
 			return err
 		}
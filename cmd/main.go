@@ -16,6 +16,10 @@ const (
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "run" {
+		os.Exit(run(os.Args[2:]))
+	}
+
 	// set the term to raw mode
 	initialSettings, tty := initTerm()
 	// on quit, put the term back in interactive mode
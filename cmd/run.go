@@ -0,0 +1,56 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/wcharczuk/health"
+)
+
+// run implements `health run [flags] -- <command> [args...]`, which runs a
+// command and reports its outcome to a running `health` instance or a state
+// file. It returns the command's exit code.
+func run(args []string) int {
+	flags := flag.NewFlagSet("run", flag.ContinueOnError)
+	name := flags.String("name", "", "The heartbeat `name` to report as, defaults to the command's base name.")
+	report := flags.String("report", "", "The base url of a running health instance to report to, e.g. http://monitor:8080.")
+	token := flags.String("token", os.Getenv("HEALTH_HEARTBEAT_TOKEN"), "The heartbeat token of the health instance to report to, defaults to $HEALTH_HEARTBEAT_TOKEN.")
+	stateFile := flags.String("state-file", "", "A file to append the run to, read by heartbeat:// hosts with a stateFile.")
+	lines := flags.Int("lines", health.DefaultJobOutputLines, "The number of trailing output lines to record.")
+	timeout := flags.Duration("timeout", health.DefaultJobReportTimeout, "How long to wait for the health instance to accept the report.")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: health run [flags] -- <command> [args...]\n")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() == 0 || (len(*report) == 0 && len(*stateFile) == 0) {
+		flags.Usage()
+		return 2
+	}
+
+	command := flags.Args()
+	if len(*name) == 0 {
+		*name = filepath.Base(command[0])
+	}
+
+	result := health.RunJob(*name, command, *lines, os.Stdout, os.Stderr)
+	if len(*report) > 0 {
+		if err := health.ReportJobRun(*report, *token, *timeout, result); err != nil {
+			fmt.Fprintf(os.Stderr, "health: %v\n", err)
+		}
+	}
+	if len(*stateFile) > 0 {
+		if err := health.AppendJobRun(*stateFile, result); err != nil {
+			fmt.Fprintf(os.Stderr, "health: %v\n", err)
+		}
+	}
+
+	if result.ExitCode < 0 {
+		return 1
+	}
+	return result.ExitCode
+}
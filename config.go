@@ -21,6 +21,12 @@ const (
 	DefaultPollInterval = 5000 * time.Millisecond
 	// DefaultRefreshInterval is the default time between screen refreshes.
 	DefaultRefreshInterval = 250 * time.Millisecond
+	// DefaultCertWarningDays is the default number of days before certificate expiry to warn.
+	DefaultCertWarningDays = 14
+	// DefaultExpectStatus is the default set of acceptable http status codes.
+	DefaultExpectStatus = "200"
+	// DefaultMaxRedirects is the default number of redirects to follow.
+	DefaultMaxRedirects = 10
 
 	// ExtensionJSON is the json extension.
 	ExtensionJSON = ".json"
@@ -42,23 +48,44 @@ func NewConfig() *Config {
 
 // NewConfigFromFlags parses commandline flags into a config object.
 func NewConfigFromFlags() (*Config, error) {
-	var hosts HostsFlag
-	flag.Var(&hosts, "host", "Host(s) to ping.")
-	pollInterval := flag.Duration("interval", DefaultPollInterval, "Server polling interval as a duration")
-	configFilePath := flag.String("config", "", "Load configuration from a file.")
+	return newConfigFromFlagSet(flag.CommandLine, os.Args[1:])
+}
 
-	flag.Parse()
+// newConfigFromFlagSet parses flags into a config object, loading `--config`
+// first if it is given and then applying any flags that were set explicitly.
+func newConfigFromFlagSet(flags *flag.FlagSet, args []string) (*Config, error) {
+	var hosts HostsFlag
+	flags.Var(&hosts, "host", "Host(s) to ping.")
+	pollInterval := flags.Duration("interval", DefaultPollInterval, "Server polling interval as a duration")
+	configFilePath := flags.String("config", "", "Load configuration from a file.")
+	verbose := flags.Bool("verbose", false, "Show a breakdown of probe timings per host.")
+	listen := flags.String("listen", "", "The `address` to serve heartbeats on, e.g. :8080.")
 
-	if configFilePath != nil && len(*configFilePath) != 0 {
-		return NewConfigFromPath(*configFilePath)
+	if err := flags.Parse(args); err != nil {
+		return nil, err
 	}
 
 	c := NewConfig()
-	if pollInterval != nil {
-		c.PollInterval = *pollInterval
+	if len(*configFilePath) != 0 {
+		var err error
+		c, err = NewConfigFromPath(*configFilePath)
+		if err != nil {
+			return nil, err
+		}
 	}
-	if len(hosts) != 0 {
-		c.Hosts = append(c.Hosts, hosts...)
+
+	flags.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "interval":
+			c.PollInterval = *pollInterval
+		case "verbose":
+			c.Verbose = *verbose
+		case "listen":
+			c.Listen = *listen
+		}
+	})
+	for _, host := range hosts {
+		c.Hosts = append(c.Hosts, HostConfig{URL: host})
 	}
 
 	return c, nil
@@ -99,18 +126,136 @@ type Config struct {
 	RefreshInterval time.Duration `json:"refresh_interval" yaml:"refreshInterval"`
 	PollInterval    time.Duration `json:"interval" yaml:"pollInterval"`
 	PingTimeout     time.Duration `json:"ping_timeout" yaml:"pingTimeout"`
-	Hosts           []string      `json:"hosts" yaml:"hosts"`
+	Hosts           []HostConfig  `json:"hosts" yaml:"hosts"`
 	Verbose         bool          `json:"verbose" yaml:"verbose"`
+	Listen          string        `json:"listen" yaml:"listen"`
+	Groups          []GroupConfig `json:"groups" yaml:"groups"`
+
+	HeartbeatToken string `json:"heartbeat_token" yaml:"heartbeatToken"`
 }
 
 // HostNameLength returns the length of the longest host name in the config.
 func (c *Config) HostNameLength() int {
 	longestHostName := 0
 	for x := 0; x < len(c.Hosts); x++ {
-		l := len(c.Hosts[x])
+		l := len(c.Hosts[x].URL)
 		if l > longestHostName {
 			longestHostName = l
 		}
 	}
 	return longestHostName
 }
+
+// HostConfig is the configuration for a single host.
+// It can be given as either a bare url string or an object.
+type HostConfig struct {
+	URL              string          `json:"url" yaml:"url"`
+	CheckCertificate bool            `json:"check_certificate" yaml:"checkCertificate"`
+	CertWarningDays  int             `json:"cert_warning_days" yaml:"certWarningDays"`
+	Assertions       []BodyAssertion `json:"assertions" yaml:"assertions"`
+	MetricAssertions []string        `json:"metric_assertions" yaml:"metricAssertions"`
+	ExpectStatus     string          `json:"expect_status" yaml:"expectStatus"`
+	FollowRedirects  *bool           `json:"follow_redirects" yaml:"followRedirects"`
+	MaxRedirects     int             `json:"max_redirects" yaml:"maxRedirects"`
+	ExpectFinalURL   string          `json:"expect_final_url" yaml:"expectFinalURL"`
+
+	Method      string            `json:"method" yaml:"method"`
+	Headers     map[string]string `json:"headers" yaml:"headers"`
+	Body        string            `json:"body" yaml:"body"`
+	BodyFile    string            `json:"body_file" yaml:"bodyFile"`
+	BasicAuth   *BasicAuth        `json:"basic_auth" yaml:"basicAuth"`
+	BearerToken string            `json:"bearer_token" yaml:"bearerToken"`
+	Steps       []HTTPStep        `json:"steps" yaml:"steps"`
+
+	WebSocket *WebSocketConfig `json:"websocket" yaml:"websocket"`
+	TCPCheck  []TCPCheckStep   `json:"tcp_check" yaml:"tcpCheck"`
+	LogWatch  *LogWatchConfig  `json:"log_watch" yaml:"logWatch"`
+	Freshness *FreshnessConfig `json:"freshness" yaml:"freshness"`
+	Group     string           `json:"group" yaml:"group"`
+	Version   *Extraction      `json:"version" yaml:"version"`
+
+	ResolveAll      bool          `json:"resolve_all" yaml:"resolveAll"`
+	ResolveInterval time.Duration `json:"resolve_interval" yaml:"resolveInterval"`
+
+	// dialHost and dialIP pin connections to one ip, for the children of a `ResolveAll` host.
+	dialHost string
+	dialIP   string
+	// heartbeats is the store a `heartbeat://` host registers with, set by `Checks`.
+	heartbeats *Heartbeats
+}
+
+// BasicAuth is a username and password for http basic authentication.
+type BasicAuth struct {
+	Username string `json:"username" yaml:"username"`
+	Password string `json:"password" yaml:"password"`
+}
+
+// GetCertWarningDays returns the cert warning threshold or a default.
+func (hc HostConfig) GetCertWarningDays() int {
+	if hc.CertWarningDays > 0 {
+		return hc.CertWarningDays
+	}
+	return DefaultCertWarningDays
+}
+
+// GetMethod returns the http method or a default.
+func (hc HostConfig) GetMethod() string {
+	if len(hc.Method) > 0 {
+		return strings.ToUpper(hc.Method)
+	}
+	return "GET"
+}
+
+// GetBody returns the request body, reading it from `BodyFile` if set.
+func (hc HostConfig) GetBody() ([]byte, error) {
+	if len(hc.BodyFile) > 0 {
+		return ioutil.ReadFile(hc.BodyFile)
+	}
+	return []byte(hc.Body), nil
+}
+
+// GetExpectStatus returns the acceptable status codes or a default.
+func (hc HostConfig) GetExpectStatus() string {
+	if len(hc.ExpectStatus) > 0 {
+		return hc.ExpectStatus
+	}
+	return DefaultExpectStatus
+}
+
+// ShouldFollowRedirects returns if redirects should be followed, which they are by default.
+func (hc HostConfig) ShouldFollowRedirects() bool {
+	if hc.FollowRedirects != nil {
+		return *hc.FollowRedirects
+	}
+	return true
+}
+
+// GetMaxRedirects returns the maximum number of redirects to follow or a default.
+func (hc HostConfig) GetMaxRedirects() int {
+	if hc.MaxRedirects > 0 {
+		return hc.MaxRedirects
+	}
+	return DefaultMaxRedirects
+}
+
+// UnmarshalJSON reads a host config from either a url string or an object.
+func (hc *HostConfig) UnmarshalJSON(data []byte) error {
+	var hostURL string
+	if err := json.Unmarshal(data, &hostURL); err == nil {
+		*hc = HostConfig{URL: hostURL}
+		return nil
+	}
+	type hostConfig HostConfig
+	return json.Unmarshal(data, (*hostConfig)(hc))
+}
+
+// UnmarshalYAML reads a host config from either a url string or an object.
+func (hc *HostConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var hostURL string
+	if err := unmarshal(&hostURL); err == nil {
+		*hc = HostConfig{URL: hostURL}
+		return nil
+	}
+	type hostConfig HostConfig
+	return unmarshal((*hostConfig)(hc))
+}
@@ -0,0 +1,63 @@
+package health
+
+import (
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/blendlabs/go-assert"
+	yaml "gopkg.in/yaml.v2"
+)
+
+func TestHostConfigUnmarshalJSON(t *testing.T) {
+	assert := assert.New(t)
+
+	config := NewConfig()
+	err := json.Unmarshal([]byte(`{"hosts":["http://foo.com",{"url":"https://bar.com","check_certificate":true,"cert_warning_days":30}]}`), config)
+	assert.Nil(err)
+	assert.Len(config.Hosts, 2)
+	assert.Equal("http://foo.com", config.Hosts[0].URL)
+	assert.False(config.Hosts[0].CheckCertificate)
+	assert.Equal(DefaultCertWarningDays, config.Hosts[0].GetCertWarningDays())
+	assert.Equal("https://bar.com", config.Hosts[1].URL)
+	assert.True(config.Hosts[1].CheckCertificate)
+	assert.Equal(30, config.Hosts[1].GetCertWarningDays())
+}
+
+func TestHostConfigUnmarshalYAML(t *testing.T) {
+	assert := assert.New(t)
+
+	config := NewConfig()
+	err := yaml.Unmarshal([]byte("hosts:\n- http://foo.com\n- url: https://bar.com\n  checkCertificate: true\n"), config)
+	assert.Nil(err)
+	assert.Len(config.Hosts, 2)
+	assert.Equal("http://foo.com", config.Hosts[0].URL)
+	assert.Equal("https://bar.com", config.Hosts[1].URL)
+	assert.True(config.Hosts[1].CheckCertificate)
+}
+
+func TestNewConfigFromFlagSet(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "health-config")
+	assert.Nil(err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "config.yml")
+	assert.Nil(ioutil.WriteFile(path, []byte("pollInterval: 30s\nlisten: \":9090\"\nhosts:\n- http://foo.com\n"), 0644))
+
+	config, err := newConfigFromFlagSet(flag.NewFlagSet("health", flag.ContinueOnError), []string{"--config", path, "--verbose", "--listen", ":8080", "--host", "http://bar.com"})
+	assert.Nil(err)
+	assert.True(config.Verbose)
+	assert.Equal(":8080", config.Listen)
+	assert.Equal(30*time.Second, config.PollInterval, "flags that weren't given should not override the file")
+	assert.Len(config.Hosts, 2)
+
+	config, err = newConfigFromFlagSet(flag.NewFlagSet("health", flag.ContinueOnError), []string{"--config", path})
+	assert.Nil(err)
+	assert.False(config.Verbose)
+	assert.Equal(":9090", config.Listen)
+}
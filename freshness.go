@@ -0,0 +1,94 @@
+package health
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// FreshnessConfig checks a timestamp in an http response, from exactly one of
+// a json path or a header, against a maximum age. Older than `warnAge` is a
+// warning and older than `maxAge` is down.
+type FreshnessConfig struct {
+	JSONPath string        `json:"json_path" yaml:"jsonPath"`
+	Header   string        `json:"header" yaml:"header"`
+	WarnAge  time.Duration `json:"warn_age" yaml:"warnAge"`
+	MaxAge   time.Duration `json:"max_age" yaml:"maxAge"`
+}
+
+// Validate returns an error if the freshness check is malformed.
+func (fc FreshnessConfig) Validate() error {
+	if (len(fc.JSONPath) > 0) == (len(fc.Header) > 0) {
+		return fmt.Errorf("freshness must set exactly one of `jsonPath` or `header`")
+	}
+	if fc.WarnAge <= 0 && fc.MaxAge <= 0 {
+		return fmt.Errorf("freshness must set `warnAge` or `maxAge`")
+	}
+	return nil
+}
+
+// name returns what the timestamp is read from, for messages.
+func (fc FreshnessConfig) name() string {
+	if len(fc.Header) > 0 {
+		return fc.Header
+	}
+	return fc.JSONPath
+}
+
+// Check reads the timestamp from a response and returns its age in the
+// details, with a warning or error if it is too old.
+func (fc FreshnessConfig) Check(res *http.Response, body []byte) ProbeResult {
+	extraction := Extraction{Name: "freshness", JSONPath: fc.JSONPath, Header: fc.Header}
+	value, err := extraction.Extract(res, body)
+	if err != nil {
+		return NewProbeResult(0, fmt.Errorf("freshness check failed: %v", err))
+	}
+	timestamp, err := ParseTimestamp(value)
+	if err != nil {
+		return NewProbeResult(0, fmt.Errorf("freshness check failed: %v", err))
+	}
+
+	age := time.Now().Sub(timestamp)
+	if age < 0 {
+		age = 0
+	}
+	ageText := FormatRoundedDuration(age, time.Second)
+
+	result := NewProbeResult(0, nil)
+	result.Details = fmt.Sprintf("%s: %s ago", fc.name(), ageText)
+	switch {
+	case fc.MaxAge > 0 && age > fc.MaxAge:
+		result = NewProbeResult(0, fmt.Errorf("%s is %s old, expected under %s", fc.name(), ageText, FormatDuration(fc.MaxAge)))
+	case fc.WarnAge > 0 && age > fc.WarnAge:
+		result.Status = StatusWarn
+	}
+	return result
+}
+
+var timestampLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05Z07:00",
+	"2006-01-02 15:04:05",
+}
+
+// ParseTimestamp parses an RFC 3339 or http date, or a unix timestamp in
+// seconds or milliseconds. Timestamps without a zone are taken as UTC.
+func ParseTimestamp(value string) (time.Time, error) {
+	if number, err := strconv.ParseFloat(value, 64); err == nil {
+		if number > 1e12 {
+			return time.Unix(0, int64(number*float64(time.Millisecond))), nil
+		}
+		return time.Unix(0, int64(number*float64(time.Second))), nil
+	}
+	for _, layout := range timestampLayouts {
+		if timestamp, err := time.Parse(layout, value); err == nil {
+			return timestamp, nil
+		}
+	}
+	if timestamp, err := http.ParseTime(value); err == nil {
+		return timestamp, nil
+	}
+	return time.Time{}, fmt.Errorf("unrecognized timestamp %q", value)
+}
@@ -0,0 +1,39 @@
+package health
+
+import (
+	"testing"
+	"time"
+
+	"github.com/blendlabs/go-assert"
+)
+
+func TestParseTimestamp(t *testing.T) {
+	assert := assert.New(t)
+
+	expected := time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC)
+	for _, value := range []string{
+		"2024-03-01T12:30:00Z",
+		"2024-03-01T13:30:00+01:00",
+		"2024-03-01T12:30:00",
+		"2024-03-01 12:30:00",
+		"Fri, 01 Mar 2024 12:30:00 GMT",
+		"1709296200",
+		"1709296200000",
+	} {
+		timestamp, err := ParseTimestamp(value)
+		assert.Nil(err)
+		assert.True(expected.Equal(timestamp), value)
+	}
+
+	_, err := ParseTimestamp("yesterday")
+	assert.NotNil(err)
+}
+
+func TestFreshnessConfigValidate(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Nil(FreshnessConfig{Header: "Last-Modified", MaxAge: time.Hour}.Validate())
+	assert.NotNil(FreshnessConfig{MaxAge: time.Hour}.Validate())
+	assert.NotNil(FreshnessConfig{Header: "Last-Modified", JSONPath: "updated", MaxAge: time.Hour}.Validate())
+	assert.NotNil(FreshnessConfig{Header: "Last-Modified"}.Validate())
+}
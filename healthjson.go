@@ -0,0 +1,122 @@
+package health
+
+import (
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+const (
+	// ContentTypeHealthJSON is the media type for health check responses,
+	// from https://tools.ietf.org/html/draft-inadarei-api-health-check.
+	ContentTypeHealthJSON = "application/health+json"
+)
+
+// IsHealthJSON returns if a response's headers declare an `application/health+json` body.
+func IsHealthJSON(header http.Header) bool {
+	mediaType, _, err := mime.ParseMediaType(header.Get("Content-Type"))
+	return err == nil && mediaType == ContentTypeHealthJSON
+}
+
+// HealthJSON is an `application/health+json` document.
+type HealthJSON struct {
+	Status    string                       `json:"status"`
+	Version   string                       `json:"version"`
+	ReleaseID string                       `json:"releaseId"`
+	Output    string                       `json:"output"`
+	Checks    map[string][]HealthComponent `json:"checks"`
+}
+
+// HealthComponent is the status of one component in a health+json document's `checks`.
+type HealthComponent struct {
+	ComponentID   string      `json:"componentId"`
+	ComponentType string      `json:"componentType"`
+	Status        string      `json:"status"`
+	ObservedValue interface{} `json:"observedValue"`
+	ObservedUnit  string      `json:"observedUnit"`
+	Output        string      `json:"output"`
+}
+
+// ParseHealthJSON parses an `application/health+json` body.
+func ParseHealthJSON(body []byte) (*HealthJSON, error) {
+	var document HealthJSON
+	if err := json.Unmarshal(body, &document); err != nil {
+		return nil, fmt.Errorf("invalid health+json: %v", err)
+	}
+	if len(document.Status) == 0 {
+		return nil, fmt.Errorf("invalid health+json: missing `status`")
+	}
+	return &document, nil
+}
+
+// ParseHealthStatus maps a health+json status onto a probe status; `pass`,
+// `ok` and `up` are up, `warn` is warn, and `fail`, `error` and `down` are down.
+func ParseHealthStatus(status string) Status {
+	switch strings.ToLower(status) {
+	case "pass", "ok", "up":
+		return StatusUp
+	case "warn":
+		return StatusWarn
+	case "fail", "error", "down":
+		return StatusDown
+	}
+	return StatusUnknown
+}
+
+// Result returns a probe result for the document. The status comes from the
+// overall `status`, the details count the components in each state, and any
+// failing components are listed in the error.
+func (hj *HealthJSON) Result() ProbeResult {
+	result := ProbeResult{Status: ParseHealthStatus(hj.Status)}
+
+	var names []string
+	for name := range hj.Checks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	counts := map[Status]int{}
+	var failing []string
+	for _, name := range names {
+		components := hj.Checks[name]
+		for _, component := range components {
+			status := ParseHealthStatus(component.Status)
+			counts[status]++
+			if status != StatusDown {
+				continue
+			}
+			failed := name
+			if len(components) > 1 && len(component.ComponentID) > 0 {
+				failed = fmt.Sprintf("%s[%s]", name, component.ComponentID)
+			}
+			if len(component.Output) > 0 {
+				failed = fmt.Sprintf("%s (%s)", failed, component.Output)
+			}
+			failing = append(failing, failed)
+		}
+	}
+
+	var summary []string
+	for _, status := range []Status{StatusUp, StatusWarn, StatusDown, StatusUnknown} {
+		if counts[status] > 0 {
+			summary = append(summary, fmt.Sprintf("%d %s", counts[status], strings.ToLower(status.String())))
+		}
+	}
+	if len(summary) > 0 {
+		result.Details = "checks: " + strings.Join(summary, ", ")
+	}
+
+	if len(failing) > 0 {
+		result.Err = fmt.Errorf("health %s, failing checks: %s", hj.Status, strings.Join(failing, ", "))
+	} else if result.Status == StatusDown || result.Status == StatusUnknown {
+		output := hj.Output
+		if len(output) == 0 {
+			output = "no output"
+		}
+		result.Err = fmt.Errorf("health %s: %s", hj.Status, output)
+	}
+	return result
+}
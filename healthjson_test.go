@@ -0,0 +1,54 @@
+package health
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/blendlabs/go-assert"
+)
+
+func TestIsHealthJSON(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.True(IsHealthJSON(http.Header{"Content-Type": {"application/health+json; charset=utf-8"}}))
+	assert.False(IsHealthJSON(http.Header{"Content-Type": {"application/json"}}))
+	assert.False(IsHealthJSON(http.Header{}))
+}
+
+func TestHealthJSONResult(t *testing.T) {
+	assert := assert.New(t)
+
+	document, err := ParseHealthJSON([]byte(`{
+		"status": "warn",
+		"checks": {
+			"uptime": [{"status": "pass", "observedValue": 1209600}],
+			"postgres:responseTime": [
+				{"componentId": "primary", "status": "pass"},
+				{"componentId": "replica", "status": "fail", "output": "connection refused"}
+			],
+			"cache": [{"status": "warn"}]
+		}
+	}`))
+	assert.Nil(err)
+
+	result := document.Result()
+	assert.Equal(StatusWarn, result.Status)
+	assert.Equal("checks: 2 up, 1 warn, 1 down", result.Details)
+	assert.NotNil(result.Err)
+	assert.Equal("health warn, failing checks: postgres:responseTime[replica] (connection refused)", result.Err.Error())
+
+	document, err = ParseHealthJSON([]byte(`{"status": "fail", "output": "out of disk"}`))
+	assert.Nil(err)
+	result = document.Result()
+	assert.Equal(StatusDown, result.Status)
+	assert.Equal("health fail: out of disk", result.Err.Error())
+
+	document, err = ParseHealthJSON([]byte(`{"status": "pass", "checks": {"db": [{"status": "pass"}]}}`))
+	assert.Nil(err)
+	result = document.Result()
+	assert.Equal(StatusUp, result.Status)
+	assert.Nil(result.Err)
+
+	_, err = ParseHealthJSON([]byte(`{"checks": {}}`))
+	assert.NotNil(err)
+}
@@ -0,0 +1,139 @@
+package health
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// HeartbeatPath is the path prefix jobs hit to report in, e.g. `/heartbeat/nightly-backup`.
+	HeartbeatPath = "/heartbeat/"
+	// MaxJobRunBytes is the largest job run body a heartbeat will accept.
+	MaxJobRunBytes = 1 << 20
+)
+
+// NewHeartbeats returns a new heartbeat store.
+func NewHeartbeats() *Heartbeats {
+	return &Heartbeats{
+		registered: map[string]bool{},
+		lastSeen:   map[string]time.Time{},
+		lastRun:    map[string]JobRun{},
+	}
+}
+
+// Heartbeats records when each named job last reported in, and the outcome
+// of its last run if it was reported by `health run`.
+type Heartbeats struct {
+	sync.Mutex
+	// Token, if set, must be sent with each heartbeat as a bearer token or a `token` query parameter.
+	Token string
+
+	registered map[string]bool
+	lastSeen   map[string]time.Time
+	lastRun    map[string]JobRun
+}
+
+// Register adds a name that heartbeats will be accepted for.
+func (hb *Heartbeats) Register(name string) {
+	hb.Lock()
+	defer hb.Unlock()
+	hb.registered[name] = true
+}
+
+// Beat records a heartbeat for a name, returning false if the name isn't
+// registered. It clears any job run previously recorded for the name.
+func (hb *Heartbeats) Beat(name string, at time.Time) bool {
+	hb.Lock()
+	defer hb.Unlock()
+	if !hb.registered[name] {
+		return false
+	}
+	hb.lastSeen[name] = at
+	delete(hb.lastRun, name)
+	return true
+}
+
+// Record records a heartbeat with the outcome of a job run, returning false if
+// the job's name isn't registered.
+func (hb *Heartbeats) Record(run JobRun, at time.Time) bool {
+	hb.Lock()
+	defer hb.Unlock()
+	if !hb.registered[run.Name] {
+		return false
+	}
+	hb.lastSeen[run.Name] = at
+	hb.lastRun[run.Name] = run
+	return true
+}
+
+// LastRun returns the outcome of a name's last reported job run, if it has one.
+func (hb *Heartbeats) LastRun(name string) (JobRun, bool) {
+	hb.Lock()
+	defer hb.Unlock()
+	run, hasRun := hb.lastRun[name]
+	return run, hasRun
+}
+
+// LastSeen returns when a name last reported in, if it has.
+func (hb *Heartbeats) LastSeen(name string) (time.Time, bool) {
+	hb.Lock()
+	defer hb.Unlock()
+	lastSeen, seen := hb.lastSeen[name]
+	return lastSeen, seen
+}
+
+// ServeHTTP records a heartbeat for `/heartbeat/<name>`. Any method is
+// accepted so jobs can use whatever is simplest, e.g. `curl -fsS`. A json
+// `JobRun` body, as posted by `health run`, also records the run's outcome.
+func (hb *Heartbeats) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	if !hb.authorized(r) {
+		http.Error(rw, "invalid heartbeat token", http.StatusUnauthorized)
+		return
+	}
+	name := strings.Trim(strings.TrimPrefix(r.URL.Path, HeartbeatPath), "/")
+	if len(name) == 0 {
+		http.Error(rw, "unknown heartbeat", http.StatusNotFound)
+		return
+	}
+
+	var known bool
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		var run JobRun
+		if err := json.NewDecoder(http.MaxBytesReader(rw, r.Body, MaxJobRunBytes)).Decode(&run); err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				http.Error(rw, fmt.Sprintf("job run is larger than %d bytes", MaxJobRunBytes), http.StatusRequestEntityTooLarge)
+				return
+			}
+			http.Error(rw, fmt.Sprintf("invalid job run: %v", err), http.StatusBadRequest)
+			return
+		}
+		run.Name = name
+		known = hb.Record(run, time.Now())
+	} else {
+		known = hb.Beat(name, time.Now())
+	}
+	if !known {
+		http.Error(rw, "unknown heartbeat", http.StatusNotFound)
+		return
+	}
+	rw.Write([]byte("ok\n"))
+}
+
+// authorized returns if a request carries the token, if one is required.
+func (hb *Heartbeats) authorized(r *http.Request) bool {
+	if len(hb.Token) == 0 {
+		return true
+	}
+	token := r.URL.Query().Get("token")
+	if bearer := r.Header.Get("Authorization"); strings.HasPrefix(bearer, "Bearer ") {
+		token = strings.TrimPrefix(bearer, "Bearer ")
+	}
+	return subtle.ConstantTimeCompare([]byte(token), []byte(hb.Token)) == 1
+}
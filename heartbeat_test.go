@@ -0,0 +1,122 @@
+package health
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/blendlabs/go-assert"
+)
+
+func TestHeartbeatsServeHTTP(t *testing.T) {
+	assert := assert.New(t)
+
+	heartbeats := NewHeartbeats()
+	heartbeats.Register("nightly-backup")
+
+	rw := httptest.NewRecorder()
+	heartbeats.ServeHTTP(rw, httptest.NewRequest("POST", "/heartbeat/nightly-backup", nil))
+	assert.Equal(http.StatusOK, rw.Code)
+	lastSeen, seen := heartbeats.LastSeen("nightly-backup")
+	assert.True(seen)
+	assert.True(time.Now().Sub(lastSeen) < time.Second)
+
+	rw = httptest.NewRecorder()
+	heartbeats.ServeHTTP(rw, httptest.NewRequest("GET", "/heartbeat/unknown", nil))
+	assert.Equal(http.StatusNotFound, rw.Code)
+	_, seen = heartbeats.LastSeen("unknown")
+	assert.False(seen)
+}
+
+func TestHeartbeatsServeHTTPToken(t *testing.T) {
+	assert := assert.New(t)
+
+	heartbeats := NewHeartbeats()
+	heartbeats.Register("nightly-backup")
+	heartbeats.Token = "s3cret"
+
+	rw := httptest.NewRecorder()
+	heartbeats.ServeHTTP(rw, httptest.NewRequest("POST", "/heartbeat/nightly-backup", nil))
+	assert.Equal(http.StatusUnauthorized, rw.Code)
+	_, seen := heartbeats.LastSeen("nightly-backup")
+	assert.False(seen)
+
+	rw = httptest.NewRecorder()
+	heartbeats.ServeHTTP(rw, httptest.NewRequest("POST", "/heartbeat/nightly-backup?token=s3cret", nil))
+	assert.Equal(http.StatusOK, rw.Code)
+
+	rw = httptest.NewRecorder()
+	req := httptest.NewRequest("POST", "/heartbeat/nightly-backup", nil)
+	req.Header.Set("Authorization", "Bearer s3cret")
+	heartbeats.ServeHTTP(rw, req)
+	assert.Equal(http.StatusOK, rw.Code)
+}
+
+func TestHeartbeatsServeHTTPBodyLimit(t *testing.T) {
+	assert := assert.New(t)
+
+	heartbeats := NewHeartbeats()
+	heartbeats.Register("nightly-backup")
+
+	body := `{"output":"` + strings.Repeat("x", MaxJobRunBytes) + `"}`
+	req := httptest.NewRequest("POST", "/heartbeat/nightly-backup", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rw := httptest.NewRecorder()
+	heartbeats.ServeHTTP(rw, req)
+	assert.Equal(http.StatusRequestEntityTooLarge, rw.Code)
+	_, hasRun := heartbeats.LastRun("nightly-backup")
+	assert.False(hasRun)
+}
+
+func TestChecksHeartbeatsAreSeparate(t *testing.T) {
+	assert := assert.New(t)
+
+	config := NewConfig()
+	config.Listen = "127.0.0.1:0"
+	config.Hosts = []HostConfig{{URL: "heartbeat://nightly-backup?period=24h"}}
+	first, err := NewChecksFromConfig(config)
+	assert.Nil(err)
+	defer first.listener.Close()
+	second, err := NewChecksFromConfig(config)
+	assert.Nil(err)
+	defer second.listener.Close()
+
+	assert.True(first.Heartbeats().Beat("nightly-backup", time.Now()))
+	_, seen := second.Heartbeats().LastSeen("nightly-backup")
+	assert.False(seen)
+}
+
+func TestChecksHeartbeatWithoutReporter(t *testing.T) {
+	assert := assert.New(t)
+
+	config := NewConfig()
+	config.Hosts = []HostConfig{{URL: "heartbeat://nightly-backup?period=24h"}}
+	_, err := NewChecksFromConfig(config)
+	assert.NotNil(err)
+	assert.Contains("can never receive a heartbeat", err.Error())
+
+	config.Hosts = []HostConfig{{URL: "heartbeat://nightly-backup?period=24h&stateFile=/var/lib/health/runs.jsonl"}}
+	_, err = NewChecksFromConfig(config)
+	assert.Nil(err)
+}
+
+func TestChecksServeErr(t *testing.T) {
+	assert := assert.New(t)
+
+	config := NewConfig()
+	config.Listen = "127.0.0.1:0"
+	checks, err := NewChecksFromConfig(config)
+	assert.Nil(err)
+	assert.Nil(checks.ServeErr())
+
+	checks.listener.Close()
+	checks.serve()
+	assert.NotNil(checks.ServeErr())
+
+	buf := bytes.NewBuffer(nil)
+	assert.Nil(checks.WriteStatus(buf))
+	assert.Contains("not receiving heartbeats", buf.String())
+}
@@ -4,12 +4,10 @@ import (
 	"bytes"
 	"fmt"
 	"io"
-	"net/http"
 	"net/url"
 	"sort"
 	"time"
 
-	"github.com/blendlabs/go-request"
 	"github.com/blendlabs/go-util"
 	"github.com/blendlabs/go-util/collections"
 )
@@ -20,25 +18,44 @@ var (
 	label75th     = util.ColorLightBlack.Apply("75th")
 	labelAverage  = util.ColorLightBlack.Apply("Average")
 	labelLast     = util.ColorLightBlack.Apply("Last")
+	labelLastSeen = util.ColorLightBlack.Apply("Last Seen")
 	labelUptime   = util.ColorLightBlack.Apply("Uptime")
 	unknownStatus = util.ColorLightBlack.Apply("UNKNOWN")
 	statusUP      = util.ColorGreen.Apply("UP")
+	statusWARN    = util.ColorYellow.Apply("WARN")
 	statusDOWN    = util.ColorRed.Apply("DOWN")
 )
 
-// NewHost returns a new host.
+// NewHost returns a new host for a url with the default options.
 func NewHost(host string, timeout time.Duration, maxStats int) (*Host, error) {
-	hostURL, err := url.Parse(host)
+	return NewHostFromConfig(&HostConfig{URL: host}, timeout, maxStats)
+}
+
+// NewHostFromConfig returns a new host with per-host options.
+func NewHostFromConfig(config *HostConfig, timeout time.Duration, maxStats int) (*Host, error) {
+	hostURL, err := ParseHostURL(config.URL)
+	if err != nil {
+		return nil, err
+	}
+	if config.ResolveAll {
+		if err := validateResolveAll(hostURL); err != nil {
+			return nil, err
+		}
+	}
+	prober, err := NewProber(hostURL, config)
 	if err != nil {
 		return nil, err
 	}
 	return &Host{
 		url:          hostURL,
+		config:       config,
+		group:        config.Group,
+		prober:       prober,
 		maxStats:     maxStats,
 		timeout:      timeout,
 		startedAtUTC: time.Now().UTC(),
-		transport:    &http.Transport{},
 		stats:        collections.NewRingBufferWithCapacity(maxStats),
+		timings:      map[string]collections.Queue{},
 		errs:         collections.NewRingBuffer(),
 	}, nil
 }
@@ -50,16 +67,33 @@ type Host struct {
 	downAt       *time.Time
 	downtime     time.Duration
 	stats        collections.Queue
-	transport    *http.Transport
-	req          *request.Request
+	timings      map[string]collections.Queue
+	timingNames  []string
+	prober       Prober
+	status       Status
+	details      string
+	metrics      []Metric
+	group        string
+	version      string
 	timeout      time.Duration
 	errs         collections.Queue
 	maxStats     int
+
+	// config, resolvedAt, resolveErr and children are set for a `ResolveAll` host, which
+	// has a child check for each ip its name resolves to. ip is set on the children.
+	config     *HostConfig
+	resolvedAt time.Time
+	resolveErr error
+	children   []*Host
+	ip         string
 }
 
 // SetTimeout sets the timeout used by `ping`.
 func (h *Host) SetTimeout(timeout time.Duration) {
 	h.timeout = timeout
+	for _, child := range h.children {
+		child.SetTimeout(timeout)
+	}
 }
 
 // URL returns the URL.
@@ -67,6 +101,29 @@ func (h Host) URL() *url.URL {
 	return h.url
 }
 
+// Name returns the display name for the host, which is its url with any password
+// redacted, and the ip it dials for the children of a `ResolveAll` host.
+func (h Host) Name() string {
+	if len(h.ip) > 0 {
+		return fmt.Sprintf("%s [%s]", h.url.Redacted(), h.ip)
+	}
+	return h.url.Redacted()
+}
+
+// Children returns the per ip checks for a `ResolveAll` host.
+func (h Host) Children() []*Host {
+	return h.children
+}
+
+// statusName returns the name shown on the status line, where the children of
+// a `ResolveAll` host are shown indented under it by their ip.
+func (h Host) statusName() string {
+	if len(h.ip) > 0 {
+		return "  - " + h.ip
+	}
+	return h.Name()
+}
+
 // IsUp returns if the host is up or not.
 func (h Host) IsUp() bool {
 	return h.downAt == nil
@@ -109,37 +166,33 @@ func (h *Host) AddTiming(elapsed time.Duration) {
 	h.stats.Enqueue(elapsed)
 }
 
-func (h *Host) ensureRequest() *request.Request {
-	if h.req != nil {
-		return h.req
+// AddPhaseTimings adds the timings for each phase of a probe to their stats collections.
+func (h *Host) AddPhaseTimings(timings []Timing) {
+	for _, timing := range timings {
+		stats, hasStats := h.timings[timing.Name]
+		if !hasStats {
+			stats = collections.NewRingBufferWithCapacity(h.maxStats)
+			h.timings[timing.Name] = stats
+			h.timingNames = append(h.timingNames, timing.Name)
+		}
+		if stats.Len() >= h.maxStats {
+			stats.Dequeue()
+		}
+		stats.Enqueue(timing.Elapsed)
 	}
+}
 
-	req := request.New().
-		AsGet().
-		WithKeepAlives().
-		WithURL(h.url.String()).
-		WithTimeout(h.timeout)
-
-	h.req = req
-	return req
+// Probe runs the host's prober with the configured timeout.
+func (h *Host) Probe() ProbeResult {
+	return h.prober.Probe(h.timeout)
 }
 
 // Ping pings a host and returns the elapsed time and any errors.
+//
+// Deprecated: use `Probe`, which also reports the host's status, details and phase timings.
 func (h *Host) Ping() (time.Duration, error) {
-	req := h.ensureRequest()
-
-	begin := time.Now()
-	meta, err := req.ExecuteWithMeta()
-	elapsed := time.Now().Sub(begin)
-	if err != nil {
-		return elapsed, err
-	}
-
-	if meta.StatusCode > http.StatusOK {
-		return elapsed, fmt.Errorf("non-200 returned from endpoint")
-	}
-
-	return elapsed, nil
+	result := h.Probe()
+	return result.Elapsed, result.Err
 }
 
 // Mean returns the average duration.
@@ -154,6 +207,19 @@ func (h Host) Mean() time.Duration {
 	return accum / time.Duration(h.stats.Len())
 }
 
+// PhaseMean returns the average duration for a phase of the probe.
+func (h Host) PhaseMean(name string) time.Duration {
+	stats, hasStats := h.timings[name]
+	if !hasStats || stats.Len() == 0 {
+		return 0
+	}
+	var accum time.Duration
+	stats.Each(func(v interface{}) {
+		accum += v.(time.Duration)
+	})
+	return accum / time.Duration(stats.Len())
+}
+
 // Percentile returns the nth percentile of timing stats.
 func (h Host) Percentile(percentile float64) time.Duration {
 	var values []time.Duration
@@ -181,9 +247,20 @@ func (h Host) Percentile(percentile float64) time.Duration {
 	return values[i-1]
 }
 
+// statusLabel returns the colored label for a host that isn't down.
+func (h Host) statusLabel() string {
+	switch h.status {
+	case StatusWarn:
+		return statusWARN
+	case StatusUnknown:
+		return unknownStatus
+	}
+	return statusUP
+}
+
 // WriteStatus writes the status line for the host.
 func (h Host) WriteStatus(hostWidth int, maxElapsed time.Duration, writer io.Writer) error {
-	host := util.ColorReset.Apply(util.String.FixedWidthLeftAligned(h.url.String(), hostWidth+2))
+	host := util.ColorReset.Apply(util.String.FixedWidthLeftAligned(h.statusName(), hostWidth+2))
 
 	uptimePCT := 1.0
 	if h.TotalDowntime() > 0 {
@@ -211,12 +288,16 @@ func (h Host) WriteStatus(hostWidth int, maxElapsed time.Duration, writer io.Wri
 
 	if !h.IsUp() {
 		downFor := time.Now().Sub(*h.downAt)
-		_, err := fmt.Fprintf(writer, "%s %6s %-6s Down For: %s\r\n", host, statusDOWN, uptimeText, FormatDuration(downFor))
+		_, err := fmt.Fprintf(writer, "%s %6s %-6s Down For: %s%s\r\n", host, statusDOWN, uptimeText, FormatDuration(downFor), h.resolveStatus())
 		return err
 	}
 
+	if passive, isPassive := h.prober.(PassiveProber); isPassive {
+		return h.writePassiveStatus(host, uptimeText, passive, writer)
+	}
+
 	if h.stats.Len() == 0 {
-		_, err := fmt.Fprintf(writer, "%s %s\r\n", host, unknownStatus)
+		_, err := fmt.Fprintf(writer, "%s %s%s\r\n", host, unknownStatus, h.resolveStatus())
 		return err
 	}
 
@@ -238,7 +319,7 @@ func (h Host) WriteStatus(hostWidth int, maxElapsed time.Duration, writer io.Wri
 	buf := bytes.NewBuffer(nil)
 	buf.WriteString(host)
 	buf.WriteRune(rune(' '))
-	buf.WriteString(fmt.Sprintf("%6s", statusUP))
+	buf.WriteString(fmt.Sprintf("%6s", h.statusLabel()))
 	buf.WriteRune(rune(' '))
 	buf.WriteString(fmt.Sprintf("%-6s", uptimeText))
 	buf.WriteRune(rune(' '))
@@ -248,6 +329,62 @@ func (h Host) WriteStatus(hostWidth int, maxElapsed time.Duration, writer io.Wri
 	buf.WriteString(fmt.Sprintf("%s: %-6s", labelAverage, FormatDuration(RoundDuration(avg, time.Millisecond))))
 	buf.WriteString(fmt.Sprintf("%s: %-6s", label99th, FormatDuration(RoundDuration(p99, time.Millisecond))))
 	buf.WriteString(fmt.Sprintf("%s: %-6s", label90th, FormatDuration(RoundDuration(p90, time.Millisecond))))
+	if len(h.details) > 0 {
+		buf.WriteRune(rune(' '))
+		buf.WriteString(util.ColorLightBlack.Apply(h.details))
+	}
+	for _, metric := range h.metrics {
+		buf.WriteRune(rune(' '))
+		buf.WriteString(metric.String())
+	}
+	buf.WriteString(h.resolveStatus())
+	buf.WriteRune(rune('\r'))
+	buf.WriteRune(rune('\n'))
+	_, err := writer.Write(buf.Bytes())
+	return err
+}
+
+// writePassiveStatus writes the status line for a host that reports in, with
+// when it was last seen in place of latency.
+func (h Host) writePassiveStatus(host, uptimeText string, passive PassiveProber, writer io.Writer) error {
+	lastSeenText := "never"
+	if lastSeen, seen := passive.LastSeen(); seen {
+		lastSeenText = "just now"
+		if age := RoundDuration(time.Now().Sub(lastSeen), time.Second); age > 0 {
+			lastSeenText = FormatDuration(age) + " ago"
+		}
+	}
+
+	buf := bytes.NewBuffer(nil)
+	buf.WriteString(host)
+	buf.WriteRune(rune(' '))
+	buf.WriteString(fmt.Sprintf("%6s", h.statusLabel()))
+	buf.WriteRune(rune(' '))
+	buf.WriteString(fmt.Sprintf("%-6s", uptimeText))
+	buf.WriteRune(rune(' '))
+	buf.WriteString(fmt.Sprintf("%s: %-12s", labelLastSeen, lastSeenText))
+	if len(h.details) > 0 {
+		buf.WriteRune(rune(' '))
+		buf.WriteString(util.ColorLightBlack.Apply(h.details))
+	}
+	buf.WriteRune(rune('\r'))
+	buf.WriteRune(rune('\n'))
+	_, err := writer.Write(buf.Bytes())
+	return err
+}
+
+// WriteTimingStatus writes the average time spent in each phase of the probe, if the prober reports phases.
+func (h Host) WriteTimingStatus(hostWidth int, writer io.Writer) error {
+	if len(h.timingNames) == 0 {
+		return nil
+	}
+
+	buf := bytes.NewBuffer(nil)
+	buf.WriteString(util.String.FixedWidthLeftAligned("", hostWidth+2))
+	for _, name := range h.timingNames {
+		buf.WriteRune(rune(' '))
+		buf.WriteString(fmt.Sprintf("%s: %-6s", util.ColorLightBlack.Apply(name), FormatDuration(RoundDuration(h.PhaseMean(name), time.Millisecond))))
+	}
 	buf.WriteRune(rune('\r'))
 	buf.WriteRune(rune('\n'))
 	_, err := writer.Write(buf.Bytes())
@@ -256,7 +393,8 @@ func (h Host) WriteStatus(hostWidth int, maxElapsed time.Duration, writer io.Wri
 
 // WriteDowntimeStatus writes downtime status if any is present.
 func (h Host) WriteDowntimeStatus(hostWidth int, writer io.Writer) error {
-	host := util.ColorReset.Apply(util.String.FixedWidthLeftAligned(h.url.String(), hostWidth+2))
+	// padded but not truncated, as the name of a `ResolveAll` child can be wider than the status column.
+	host := util.ColorReset.Apply(fmt.Sprintf("%-*s", hostWidth+2, h.Name()))
 
 	if h.TotalDowntime() > 0 {
 		totalTime := h.TotalTime()
@@ -270,7 +408,7 @@ func (h Host) WriteDowntimeStatus(hostWidth int, writer io.Writer) error {
 
 // WriteErrorStatus writes the error status.
 func (h Host) WriteErrorStatus(hostWidth int, writer io.Writer) error {
-	host := util.ColorReset.Apply(util.String.FixedWidthLeftAligned(h.url.String(), hostWidth+2))
+	host := util.ColorReset.Apply(fmt.Sprintf("%-*s", hostWidth+2, h.Name()))
 
 	buf := bytes.NewBuffer(nil)
 
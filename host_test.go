@@ -0,0 +1,83 @@
+package health
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/blendlabs/go-assert"
+	"github.com/blendlabs/go-util"
+)
+
+func TestHostPhaseTimings(t *testing.T) {
+	assert := assert.New(t)
+
+	host, err := NewHostFromConfig(&HostConfig{URL: "http://localhost"}, time.Second, 2)
+	assert.Nil(err)
+
+	host.AddPhaseTimings([]Timing{{Name: "dns", Elapsed: time.Millisecond}, {Name: "ttfb", Elapsed: 10 * time.Millisecond}})
+	host.AddPhaseTimings([]Timing{{Name: "dns", Elapsed: 3 * time.Millisecond}, {Name: "ttfb", Elapsed: 20 * time.Millisecond}})
+	host.AddPhaseTimings([]Timing{{Name: "dns", Elapsed: 5 * time.Millisecond}, {Name: "ttfb", Elapsed: 30 * time.Millisecond}})
+
+	assert.Equal(4*time.Millisecond, host.PhaseMean("dns"))
+	assert.Equal(25*time.Millisecond, host.PhaseMean("ttfb"))
+	assert.Equal(time.Duration(0), host.PhaseMean("connect"))
+
+	buf := bytes.NewBuffer(nil)
+	assert.Nil(host.WriteTimingStatus(16, buf))
+	assert.Contains("4ms", buf.String())
+	assert.Contains("25ms", buf.String())
+}
+
+func TestHostPing(t *testing.T) {
+	assert := assert.New(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/down" {
+			rw.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	host, err := NewHost(server.URL, time.Second, 2)
+	assert.Nil(err)
+	elapsed, err := host.Ping()
+	assert.Nil(err)
+	assert.True(elapsed > 0)
+
+	host, err = NewHostFromConfig(&HostConfig{URL: server.URL + "/down"}, time.Second, 2)
+	assert.Nil(err)
+	_, err = host.Ping()
+	assert.NotNil(err)
+}
+
+func TestChecksPingUnknownCountsAsUp(t *testing.T) {
+	assert := assert.New(t)
+
+	config := NewConfig()
+	config.Hosts = []HostConfig{{URL: "heartbeat://etl-pipeline?period=1h&stateFile=" + filepath.Join(t.TempDir(), "runs.jsonl")}}
+	checks, err := NewChecksFromConfig(config)
+	assert.Nil(err)
+
+	host := checks.Hosts()[0]
+	assert.Nil(checks.Ping(host))
+	assert.Equal(StatusUnknown, host.status)
+	assert.True(host.IsUp())
+	assert.Equal(time.Duration(0), host.TotalDowntime())
+}
+
+func TestHostWriteStatusSeparatesDetails(t *testing.T) {
+	assert := assert.New(t)
+
+	host, err := NewHost("https://fooserver.com", time.Second, 2)
+	assert.Nil(err)
+	host.AddTiming(1200 * time.Millisecond)
+	host.details = "cert: 12d left"
+
+	buf := bytes.NewBuffer(nil)
+	assert.Nil(host.WriteStatus(16, 1200*time.Millisecond, buf))
+	assert.Contains("1s200ms "+util.ColorLightBlack.Apply("cert: 12d left"), buf.String())
+}
@@ -0,0 +1,181 @@
+package health
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultJobOutputLines is the number of trailing output lines kept for a job run.
+	DefaultJobOutputLines = 20
+
+	// DefaultJobReportTimeout bounds how long reporting a job run can take.
+	DefaultJobReportTimeout = 10 * time.Second
+
+	// maxJobOutputBytes bounds the output buffered while a job runs.
+	maxJobOutputBytes = 64 * 1024
+)
+
+// JobRun is the outcome of a wrapped command, as recorded by `health run`.
+type JobRun struct {
+	Name      string        `json:"name"`
+	StartedAt time.Time     `json:"started_at"`
+	Duration  time.Duration `json:"duration"`
+	ExitCode  int           `json:"exit_code"`
+	Output    string        `json:"output"`
+}
+
+// FinishedAt returns when the run finished.
+func (jr JobRun) FinishedAt() time.Time {
+	return jr.StartedAt.Add(jr.Duration)
+}
+
+// Err returns an error describing the run if it failed, including the last line of its output.
+func (jr JobRun) Err() error {
+	if jr.ExitCode == 0 {
+		return nil
+	}
+	err := fmt.Sprintf("%s exited with %d after %v", jr.Name, jr.ExitCode, jr.Duration.Round(time.Millisecond))
+	lines := strings.Split(strings.TrimSpace(jr.Output), "\n")
+	if last := strings.TrimSpace(lines[len(lines)-1]); len(last) > 0 {
+		err = fmt.Sprintf("%s: %s", err, last)
+	}
+	return fmt.Errorf("%s", err)
+}
+
+// RunJob runs a command, passing its output through to `stdout` and `stderr`,
+// and records its start, duration, exit code and the last `outputLines` lines of
+// its combined output. A command that can't be started or is killed by a
+// signal has an exit code of -1.
+func RunJob(name string, args []string, outputLines int, stdout, stderr io.Writer) JobRun {
+	output := new(tailBuffer)
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = io.MultiWriter(stdout, output)
+	cmd.Stderr = io.MultiWriter(stderr, output)
+
+	run := JobRun{Name: name, StartedAt: time.Now().UTC()}
+	err := cmd.Run()
+	run.Duration = time.Now().UTC().Sub(run.StartedAt)
+	if err != nil {
+		if exitErr, isExitErr := err.(*exec.ExitError); isExitErr {
+			run.ExitCode = exitErr.ExitCode()
+		} else {
+			run.ExitCode = -1
+			fmt.Fprintf(output, "%v\n", err)
+		}
+	}
+	run.Output = output.Tail(outputLines)
+	return run
+}
+
+// ReportJobRun posts a run to the heartbeat endpoint of a running `health`
+// instance at `baseURL`, e.g. `http://monitor:8080`, with `token` as a bearer
+// token if it is set. The report fails if it takes longer than `timeout`, so a
+// monitor that stops answering can't hold up the job.
+func ReportJobRun(baseURL, token string, timeout time.Duration, run JobRun) error {
+	body, err := json.Marshal(run)
+	if err != nil {
+		return err
+	}
+	reportURL := strings.TrimSuffix(baseURL, "/") + HeartbeatPath + run.Name
+	req, err := http.NewRequest("POST", reportURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if len(token) > 0 {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	client := &http.Client{Timeout: timeout}
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("reporting to %s failed with status code %d", reportURL, res.StatusCode)
+	}
+	return nil
+}
+
+// AppendJobRun appends a run to a state file as a line of json.
+func AppendJobRun(path string, run JobRun) error {
+	line, err := json.Marshal(run)
+	if err != nil {
+		return err
+	}
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	_, err = file.Write(append(line, '\n'))
+	return err
+}
+
+// LastJobRun returns the most recent run of a job in a state file written by
+// `AppendJobRun`, skipping lines that don't parse.
+func LastJobRun(path, name string) (JobRun, bool, error) {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return JobRun{}, false, nil
+	}
+	if err != nil {
+		return JobRun{}, false, err
+	}
+	defer file.Close()
+
+	var last JobRun
+	var found bool
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*maxJobOutputBytes)
+	for scanner.Scan() {
+		var run JobRun
+		if err := json.Unmarshal(scanner.Bytes(), &run); err != nil || run.Name != name {
+			continue
+		}
+		if !found || run.FinishedAt().After(last.FinishedAt()) {
+			last, found = run, true
+		}
+	}
+	return last, found, scanner.Err()
+}
+
+// tailBuffer keeps the end of everything written to it. It is safe to share
+// between a command's stdout and stderr.
+type tailBuffer struct {
+	sync.Mutex
+	data []byte
+}
+
+// Write appends to the buffer, dropping the oldest data past `maxJobOutputBytes`.
+func (tb *tailBuffer) Write(data []byte) (int, error) {
+	tb.Lock()
+	defer tb.Unlock()
+	tb.data = append(tb.data, data...)
+	if overflow := len(tb.data) - maxJobOutputBytes; overflow > 0 {
+		tb.data = tb.data[overflow:]
+	}
+	return len(data), nil
+}
+
+// Tail returns the last `lines` lines written.
+func (tb *tailBuffer) Tail(lines int) string {
+	tb.Lock()
+	defer tb.Unlock()
+	all := strings.Split(strings.TrimRight(string(tb.data), "\n"), "\n")
+	if len(all) > lines {
+		all = all[len(all)-lines:]
+	}
+	return strings.Join(all, "\n")
+}
@@ -0,0 +1,101 @@
+package health
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/blendlabs/go-assert"
+)
+
+func TestRunJob(t *testing.T) {
+	assert := assert.New(t)
+
+	var stdout, stderr bytes.Buffer
+	run := RunJob("backup", []string{"sh", "-c", "echo one; echo two; echo three; exit 3"}, 2, &stdout, &stderr)
+	assert.Equal("backup", run.Name)
+	assert.Equal(3, run.ExitCode)
+	assert.False(run.StartedAt.IsZero())
+	assert.Equal("one\ntwo\nthree\n", stdout.String())
+	assert.Equal("two\nthree", run.Output)
+	assert.NotNil(run.Err())
+	assert.Contains("backup exited with 3", run.Err().Error())
+	assert.Contains(": three", run.Err().Error())
+
+	run = RunJob("backup", []string{"sh", "-c", "echo disk full >&2"}, 2, &stdout, &stderr)
+	assert.Equal(0, run.ExitCode)
+	assert.Nil(run.Err())
+	assert.Equal("disk full\n", stderr.String())
+	assert.Equal("disk full", run.Output)
+
+	run = RunJob("missing", []string{"/does/not/exist"}, 2, &stdout, &stderr)
+	assert.Equal(-1, run.ExitCode)
+	assert.NotEmpty(run.Output)
+}
+
+func TestJobRunStateFile(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "health-jobs")
+	assert.Nil(err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "runs.jsonl")
+
+	_, found, err := LastJobRun(path, "backup")
+	assert.Nil(err)
+	assert.False(found)
+
+	started := time.Now().UTC().Add(-time.Hour)
+	assert.Nil(AppendJobRun(path, JobRun{Name: "backup", StartedAt: started, Duration: time.Minute, ExitCode: 1}))
+	assert.Nil(AppendJobRun(path, JobRun{Name: "backup", StartedAt: started.Add(30 * time.Minute), Duration: time.Minute}))
+	assert.Nil(AppendJobRun(path, JobRun{Name: "reports", StartedAt: started.Add(40 * time.Minute), ExitCode: 2}))
+
+	run, found, err := LastJobRun(path, "backup")
+	assert.Nil(err)
+	assert.True(found)
+	assert.Equal(0, run.ExitCode)
+	assert.Equal(started.Add(31*time.Minute).Unix(), run.FinishedAt().Unix())
+}
+
+func TestReportJobRun(t *testing.T) {
+	assert := assert.New(t)
+
+	heartbeats := NewHeartbeats()
+	heartbeats.Register("backup")
+	server := httptest.NewServer(heartbeats)
+	defer server.Close()
+
+	assert.Nil(ReportJobRun(server.URL, "", time.Second, JobRun{Name: "backup", StartedAt: time.Now(), ExitCode: 4, Output: "disk full"}))
+	run, hasRun := heartbeats.LastRun("backup")
+	assert.True(hasRun)
+	assert.Equal(4, run.ExitCode)
+	assert.Equal("disk full", run.Output)
+	_, seen := heartbeats.LastSeen("backup")
+	assert.True(seen)
+
+	assert.NotNil(ReportJobRun(server.URL, "", time.Second, JobRun{Name: "unknown"}))
+
+	heartbeats.Token = "s3cret"
+	assert.NotNil(ReportJobRun(server.URL, "", time.Second, JobRun{Name: "backup"}))
+	assert.Nil(ReportJobRun(server.URL, "s3cret", time.Second, JobRun{Name: "backup"}))
+}
+
+func TestReportJobRunTimeout(t *testing.T) {
+	assert := assert.New(t)
+
+	done := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		<-done
+	}))
+	defer server.Close()
+	defer close(done)
+
+	begin := time.Now()
+	assert.NotNil(ReportJobRun(server.URL, "", 50*time.Millisecond, JobRun{Name: "backup"}))
+	assert.True(time.Now().Sub(begin) < time.Second)
+}
@@ -0,0 +1,66 @@
+package health
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ParseJSON parses a json document for use with `JSONPath`.
+func ParseJSON(body []byte) (interface{}, error) {
+	var document interface{}
+	if err := json.Unmarshal(body, &document); err != nil {
+		return nil, fmt.Errorf("invalid json: %v", err)
+	}
+	return document, nil
+}
+
+// JSONPath returns the value at a dotted path in a parsed json document, and
+// if it was found. Paths look like `$.status`, `checks.db.status`,
+// `items[0].name` or `items.0.name`; the leading `$.` is optional.
+func JSONPath(document interface{}, path string) (interface{}, bool) {
+	path = strings.TrimPrefix(strings.TrimPrefix(path, "$"), ".")
+	path = strings.Replace(path, "[", ".", -1)
+	path = strings.Replace(path, "]", "", -1)
+	if len(path) == 0 {
+		return document, true
+	}
+
+	current := document
+	for _, key := range strings.Split(path, ".") {
+		switch typed := current.(type) {
+		case map[string]interface{}:
+			value, hasValue := typed[key]
+			if !hasValue {
+				return nil, false
+			}
+			current = value
+		case []interface{}:
+			index, err := strconv.Atoi(key)
+			if err != nil || index < 0 || index >= len(typed) {
+				return nil, false
+			}
+			current = typed[index]
+		default:
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+// FormatJSONValue formats a json value for comparison and display.
+func FormatJSONValue(value interface{}) string {
+	switch typed := value.(type) {
+	case string:
+		return typed
+	case float64:
+		return strconv.FormatFloat(typed, 'f', -1, 64)
+	case nil:
+		return "null"
+	case map[string]interface{}, []interface{}:
+		contents, _ := json.Marshal(typed)
+		return string(contents)
+	}
+	return fmt.Sprint(value)
+}
@@ -0,0 +1,35 @@
+package health
+
+import (
+	"testing"
+
+	"github.com/blendlabs/go-assert"
+)
+
+func TestJSONPath(t *testing.T) {
+	assert := assert.New(t)
+
+	document, err := ParseJSON([]byte(`{"status":"ok","checks":{"db":{"latency":12.5}},"items":[{"name":"a"},{"name":"b"}]}`))
+	assert.Nil(err)
+
+	value, found := JSONPath(document, "$.status")
+	assert.True(found)
+	assert.Equal("ok", value)
+
+	value, found = JSONPath(document, "checks.db.latency")
+	assert.True(found)
+	assert.Equal("12.5", FormatJSONValue(value))
+
+	value, found = JSONPath(document, "items[1].name")
+	assert.True(found)
+	assert.Equal("b", value)
+
+	value, found = JSONPath(document, "items.0.name")
+	assert.True(found)
+	assert.Equal("a", value)
+
+	_, found = JSONPath(document, "items[2].name")
+	assert.False(found)
+	_, found = JSONPath(document, "checks.cache")
+	assert.False(found)
+}
@@ -0,0 +1,148 @@
+package health
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Status is the outcome of a probe.
+type Status int
+
+const (
+	// StatusUp means the probe succeeded.
+	StatusUp Status = iota
+	// StatusWarn means the probe succeeded but something needs attention.
+	StatusWarn
+	// StatusDown means the probe failed.
+	StatusDown
+	// StatusUnknown means the probe couldn't determine the state of the host.
+	StatusUnknown
+)
+
+// String returns the display name for the status.
+func (s Status) String() string {
+	switch s {
+	case StatusUp:
+		return "UP"
+	case StatusWarn:
+		return "WARN"
+	case StatusDown:
+		return "DOWN"
+	}
+	return "UNKNOWN"
+}
+
+// Timing is the elapsed time for a named phase of a probe.
+type Timing struct {
+	Name    string
+	Elapsed time.Duration
+}
+
+// Metric is a named value reported by a probe.
+type Metric struct {
+	Name  string
+	Value float64
+	Unit  string
+}
+
+// String returns the metric as `name=valueunit`.
+func (m Metric) String() string {
+	return fmt.Sprintf("%s=%s%s", m.Name, strconv.FormatFloat(m.Value, 'f', -1, 64), m.Unit)
+}
+
+// ProbeResult is the result of a single probe.
+type ProbeResult struct {
+	Elapsed time.Duration
+	Status  Status
+	Details string
+	Timings []Timing
+	Metrics []Metric
+	Version string
+	Err     error
+}
+
+// NewProbeResult returns a result that is down if there was an error and up otherwise.
+func NewProbeResult(elapsed time.Duration, err error) ProbeResult {
+	if err != nil {
+		return ProbeResult{Elapsed: elapsed, Status: StatusDown, Err: err}
+	}
+	return ProbeResult{Elapsed: elapsed, Status: StatusUp}
+}
+
+// Prober is a type that can check a host.
+type Prober interface {
+	Probe(timeout time.Duration) ProbeResult
+}
+
+// PassiveProber is a prober for a host that reports in rather than being
+// polled, which is shown with when it was last seen instead of latency.
+type PassiveProber interface {
+	Prober
+	LastSeen() (time.Time, bool)
+}
+
+// VersionProber is a prober that can report the version of what it checks,
+// for the hosts of a group to be compared.
+type VersionProber interface {
+	Prober
+	ReportsVersion() bool
+}
+
+// ProberFactory creates a prober for a host url and its config.
+type ProberFactory func(hostURL *url.URL, config *HostConfig) (Prober, error)
+
+var (
+	probersLock sync.Mutex
+	probers     = map[string]ProberFactory{
+		"http":       NewHTTPProber,
+		"https":      NewHTTPProber,
+		"tcp":        NewTCPProber,
+		"dns":        NewDNSProber,
+		"tls":        NewTLSProber,
+		"grpc":       NewGRPCProber,
+		"grpcs":      NewGRPCProber,
+		"postgres":   NewPostgresProber,
+		"postgresql": NewPostgresProber,
+		"redis":      NewRedisProber,
+		"rediss":     NewRedisProber,
+		"exec":       NewExecProber,
+		"ws":         NewWebSocketProber,
+		"wss":        NewWebSocketProber,
+		"heartbeat":  NewHeartbeatProber,
+		"log":        NewLogProber,
+		"system":     NewSystemProber,
+		"file":       NewFileProber,
+	}
+)
+
+// RegisterProber registers a prober factory for a given url scheme.
+func RegisterProber(scheme string, factory ProberFactory) {
+	probersLock.Lock()
+	defer probersLock.Unlock()
+	probers[strings.ToLower(scheme)] = factory
+}
+
+// ParseHostURL parses a host url. `exec://` hosts are kept opaque because
+// commands like `exec:///usr/lib/nagios/plugins/check_disk -w 10%` aren't valid urls.
+func ParseHostURL(rawURL string) (*url.URL, error) {
+	if strings.HasPrefix(strings.ToLower(rawURL), "exec://") {
+		return &url.URL{Scheme: "exec", Opaque: rawURL[len("exec:"):]}, nil
+	}
+	return url.Parse(rawURL)
+}
+
+// NewProber returns a prober for a host url based on its scheme.
+func NewProber(hostURL *url.URL, config *HostConfig) (Prober, error) {
+	probersLock.Lock()
+	factory, hasFactory := probers[strings.ToLower(hostURL.Scheme)]
+	probersLock.Unlock()
+
+	if !hasFactory {
+		return nil, fmt.Errorf("unsupported host scheme: %q", hostURL.Scheme)
+	}
+	return factory(hostURL, config)
+}
@@ -0,0 +1,414 @@
+package health
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultDNSPort is the port used for resolvers that don't specify one.
+	DefaultDNSPort = "53"
+	// DefaultDNSRecordType is the record type queried if `type` isn't set.
+	DefaultDNSRecordType = "A"
+)
+
+// NewDNSProber returns a new dns prober for urls of the form
+// `dns://resolver[:port]/name?type=A&contains=10.0.0.5&equals=...`.
+func NewDNSProber(hostURL *url.URL, config *HostConfig) (Prober, error) {
+	if len(hostURL.Hostname()) == 0 {
+		return nil, fmt.Errorf("dns host must include a resolver: %s", hostURL.String())
+	}
+	name := strings.TrimPrefix(hostURL.Path, "/")
+	if len(name) == 0 {
+		return nil, fmt.Errorf("dns host must include a name to resolve: %s", hostURL.String())
+	}
+
+	port := hostURL.Port()
+	if len(port) == 0 {
+		port = DefaultDNSPort
+	}
+
+	query := hostURL.Query()
+	recordType := strings.ToUpper(query.Get("type"))
+	if len(recordType) == 0 {
+		recordType = DefaultDNSRecordType
+	}
+	if _, isSupported := dnsRecordTypes[recordType]; !isSupported {
+		return nil, fmt.Errorf("unsupported dns record type: %q", recordType)
+	}
+
+	return &DNSProber{
+		resolver:   net.JoinHostPort(hostURL.Hostname(), port),
+		name:       name,
+		recordType: recordType,
+		contains:   query["contains"],
+		equals:     query["equals"],
+	}, nil
+}
+
+// DNSProber checks a host by timing a lookup against a specific resolver and
+// optionally asserting the returned records.
+type DNSProber struct {
+	resolver   string
+	name       string
+	recordType string
+	contains   []string
+	equals     []string
+}
+
+// Probe performs the lookup and checks the answers.
+func (dp *DNSProber) Probe(timeout time.Duration) ProbeResult {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	begin := time.Now()
+	answers, err := dp.lookup(ctx)
+	elapsed := time.Now().Sub(begin)
+	if err != nil {
+		return NewProbeResult(elapsed, err)
+	}
+
+	result := NewProbeResult(elapsed, dp.check(answers))
+	result.Details = fmt.Sprintf("%s %s", dp.recordType, strings.Join(answers, ","))
+	return result
+}
+
+// lookup sends the query straight to the resolver over udp, retrying over tcp
+// if the answer is truncated. The name is queried as fully qualified, and
+// neither /etc/hosts nor the search domains from resolv.conf are used, so the
+// answers are always the resolver's.
+func (dp *DNSProber) lookup(ctx context.Context) ([]string, error) {
+	query, err := newDNSQuery(dp.name, dnsRecordTypes[dp.recordType])
+	if err != nil {
+		return nil, err
+	}
+	response, err := dp.exchange(ctx, "udp", query)
+	if err == nil && binary.BigEndian.Uint16(response[2:])&dnsFlagTruncated != 0 {
+		response, err = dp.exchange(ctx, "tcp", query)
+	}
+	if err != nil {
+		return nil, err
+	}
+	records, err := parseDNSResponse(response)
+	if err != nil {
+		return nil, fmt.Errorf("dns %s %s: %v", dp.recordType, dp.name, err)
+	}
+
+	var answers []string
+	if dp.recordType == "CNAME" {
+		// follow the chain from the name to its canonical name.
+		name := dp.name
+		for hops := 0; hops < len(records); hops++ {
+			target, found := findDNSRecord(records, name, dnsTypeCNAME)
+			if !found {
+				break
+			}
+			name = target
+		}
+		if name != dp.name {
+			answers = append(answers, name)
+		}
+	} else {
+		for _, record := range records {
+			if record.recordType == dnsRecordTypes[dp.recordType] {
+				answers = append(answers, record.value)
+			}
+		}
+	}
+	if len(answers) == 0 {
+		return nil, fmt.Errorf("dns %s %s: no records", dp.recordType, dp.name)
+	}
+
+	for index := range answers {
+		answers[index] = dp.normalize(answers[index])
+	}
+	sort.Strings(answers)
+	return answers, nil
+}
+
+// exchange sends a query to the resolver and returns its response. Over tcp
+// messages are prefixed with their length; over udp responses to other
+// queries are skipped.
+func (dp *DNSProber) exchange(ctx context.Context, network string, query []byte) ([]byte, error) {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, network, dp.resolver)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if deadline, hasDeadline := ctx.Deadline(); hasDeadline {
+		conn.SetDeadline(deadline)
+	}
+
+	if network == "tcp" {
+		message := make([]byte, 2, 2+len(query))
+		binary.BigEndian.PutUint16(message, uint16(len(query)))
+		if _, err := conn.Write(append(message, query...)); err != nil {
+			return nil, err
+		}
+		header := make([]byte, 2)
+		if _, err := io.ReadFull(conn, header); err != nil {
+			return nil, err
+		}
+		response := make([]byte, binary.BigEndian.Uint16(header))
+		if _, err := io.ReadFull(conn, response); err != nil {
+			return nil, err
+		}
+		if len(response) < dnsHeaderLength || !bytes.Equal(response[:2], query[:2]) {
+			return nil, fmt.Errorf("dns: invalid response from %s", dp.resolver)
+		}
+		return response, nil
+	}
+
+	if _, err := conn.Write(query); err != nil {
+		return nil, err
+	}
+	buffer := make([]byte, 65535)
+	for {
+		n, err := conn.Read(buffer)
+		if err != nil {
+			return nil, err
+		}
+		if n >= dnsHeaderLength && bytes.Equal(buffer[:2], query[:2]) {
+			return buffer[:n], nil
+		}
+	}
+}
+
+// check asserts the answers against the `contains` and `equals` values.
+func (dp *DNSProber) check(answers []string) error {
+	found := map[string]bool{}
+	for _, answer := range answers {
+		found[answer] = true
+	}
+
+	for _, expected := range dp.contains {
+		if !found[dp.normalize(expected)] {
+			return fmt.Errorf("dns %s %s: expected answers to contain %s, got [%s]", dp.recordType, dp.name, expected, strings.Join(answers, ", "))
+		}
+	}
+
+	if len(dp.equals) == 0 {
+		return nil
+	}
+
+	var expected []string
+	for _, value := range dp.equals {
+		expected = append(expected, dp.normalize(value))
+	}
+	sort.Strings(expected)
+	if strings.Join(expected, ",") != strings.Join(answers, ",") {
+		return fmt.Errorf("dns %s %s: expected answers to equal [%s], got [%s]", dp.recordType, dp.name, strings.Join(expected, ", "), strings.Join(answers, ", "))
+	}
+	return nil
+}
+
+// normalize canonicalizes ips, and lowercases names and strips the trailing
+// root dot so `Foo.Example.com.` and `foo.example.com` compare equal.
+func (dp *DNSProber) normalize(answer string) string {
+	switch dp.recordType {
+	case "A", "AAAA":
+		if ip := net.ParseIP(answer); ip != nil {
+			return ip.String()
+		}
+		return answer
+	case "TXT":
+		return answer
+	}
+	return strings.TrimSuffix(strings.ToLower(answer), ".")
+}
+
+// dns wire format values, from RFC 1035 and RFC 3596.
+const (
+	dnsHeaderLength    = 12
+	dnsFlagResponse    = 1 << 15
+	dnsFlagTruncated   = 1 << 9
+	dnsFlagRecursion   = 1 << 8
+	dnsClassINET       = 1
+	dnsTypeA           = 1
+	dnsTypeNS          = 2
+	dnsTypeCNAME       = 5
+	dnsTypeMX          = 15
+	dnsTypeTXT         = 16
+	dnsTypeAAAA        = 28
+	dnsMaxPointerJumps = 64
+)
+
+// dnsRecordTypes are the wire values of the supported record types.
+var dnsRecordTypes = map[string]uint16{
+	"A":     dnsTypeA,
+	"AAAA":  dnsTypeAAAA,
+	"CNAME": dnsTypeCNAME,
+	"MX":    dnsTypeMX,
+	"NS":    dnsTypeNS,
+	"TXT":   dnsTypeTXT,
+}
+
+// dnsResponseCodes describe the error response codes a resolver commonly returns.
+var dnsResponseCodes = map[uint16]string{
+	1: "format error",
+	2: "server failure",
+	3: "no such host",
+	4: "not implemented",
+	5: "refused",
+}
+
+// dnsRecord is an answer record, with its data formatted as a string.
+type dnsRecord struct {
+	name       string
+	recordType uint16
+	value      string
+}
+
+// newDNSQuery returns a recursive query for a name with a random id.
+func newDNSQuery(name string, recordType uint16) ([]byte, error) {
+	query := make([]byte, dnsHeaderLength, dnsHeaderLength+len(name)+6)
+	if _, err := rand.Read(query[:2]); err != nil {
+		return nil, err
+	}
+	binary.BigEndian.PutUint16(query[2:], dnsFlagRecursion)
+	binary.BigEndian.PutUint16(query[4:], 1)
+
+	for _, label := range strings.Split(strings.TrimSuffix(name, "."), ".") {
+		if len(label) == 0 || len(label) > 63 {
+			return nil, fmt.Errorf("dns: invalid name %q", name)
+		}
+		query = append(query, byte(len(label)))
+		query = append(query, label...)
+	}
+	query = append(query, 0, 0, 0, 0, 0)
+	binary.BigEndian.PutUint16(query[len(query)-4:], recordType)
+	binary.BigEndian.PutUint16(query[len(query)-2:], dnsClassINET)
+	return query, nil
+}
+
+// parseDNSResponse returns the answer records of a response, or an error for
+// its response code.
+func parseDNSResponse(response []byte) ([]dnsRecord, error) {
+	if len(response) < dnsHeaderLength {
+		return nil, fmt.Errorf("invalid response")
+	}
+	flags := binary.BigEndian.Uint16(response[2:])
+	if flags&dnsFlagResponse == 0 {
+		return nil, fmt.Errorf("invalid response")
+	}
+	if code := flags & 0xf; code != 0 {
+		if description, hasDescription := dnsResponseCodes[code]; hasDescription {
+			return nil, fmt.Errorf("%s", description)
+		}
+		return nil, fmt.Errorf("response code %d", code)
+	}
+
+	offset := dnsHeaderLength
+	for question := 0; question < int(binary.BigEndian.Uint16(response[4:])); question++ {
+		_, next, err := readDNSName(response, offset)
+		if err != nil {
+			return nil, err
+		}
+		offset = next + 4
+	}
+
+	var records []dnsRecord
+	for answer := 0; answer < int(binary.BigEndian.Uint16(response[6:])); answer++ {
+		name, next, err := readDNSName(response, offset)
+		if err != nil {
+			return nil, err
+		}
+		if next+10 > len(response) {
+			return nil, fmt.Errorf("truncated answer")
+		}
+		recordType := binary.BigEndian.Uint16(response[next:])
+		length := int(binary.BigEndian.Uint16(response[next+8:]))
+		data := next + 10
+		if data+length > len(response) {
+			return nil, fmt.Errorf("truncated answer")
+		}
+		offset = data + length
+
+		record := dnsRecord{name: name, recordType: recordType}
+		switch recordType {
+		case dnsTypeA, dnsTypeAAAA:
+			record.value = net.IP(response[data:offset]).String()
+		case dnsTypeCNAME, dnsTypeNS:
+			record.value, _, err = readDNSName(response, data)
+		case dnsTypeMX:
+			if length < 3 {
+				return nil, fmt.Errorf("invalid mx record")
+			}
+			record.value, _, err = readDNSName(response, data+2)
+		case dnsTypeTXT:
+			// a record's strings are joined, as `net.LookupTXT` does.
+			var text []byte
+			for index := data; index < offset; index += int(response[index]) + 1 {
+				end := index + 1 + int(response[index])
+				if end > offset {
+					return nil, fmt.Errorf("invalid txt record")
+				}
+				text = append(text, response[index+1:end]...)
+			}
+			record.value = string(text)
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// readDNSName reads a possibly compressed name at `offset`, returning it with
+// a trailing dot and the offset just past it.
+func readDNSName(message []byte, offset int) (string, int, error) {
+	var labels []string
+	next := -1
+	for jumps := 0; jumps < dnsMaxPointerJumps; {
+		if offset >= len(message) {
+			return "", 0, fmt.Errorf("invalid name")
+		}
+		length := int(message[offset])
+		switch {
+		case length == 0:
+			if next < 0 {
+				next = offset + 1
+			}
+			return strings.Join(labels, ".") + ".", next, nil
+		case length&0xc0 == 0xc0:
+			if offset+1 >= len(message) {
+				return "", 0, fmt.Errorf("invalid name")
+			}
+			if next < 0 {
+				next = offset + 2
+			}
+			offset = int(binary.BigEndian.Uint16(message[offset:]) & 0x3fff)
+			jumps++
+		default:
+			if offset+1+length > len(message) {
+				return "", 0, fmt.Errorf("invalid name")
+			}
+			labels = append(labels, string(message[offset+1:offset+1+length]))
+			offset += 1 + length
+		}
+	}
+	return "", 0, fmt.Errorf("invalid name")
+}
+
+// findDNSRecord returns the value of the record of a type for a name.
+func findDNSRecord(records []dnsRecord, name string, recordType uint16) (string, bool) {
+	for _, record := range records {
+		if record.recordType == recordType && strings.EqualFold(strings.TrimSuffix(record.name, "."), strings.TrimSuffix(name, ".")) {
+			return record.value, true
+		}
+	}
+	return "", false
+}
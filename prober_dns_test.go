@@ -0,0 +1,175 @@
+package health
+
+import (
+	"encoding/binary"
+	"net"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/blendlabs/go-assert"
+)
+
+// serveDNS answers every A query on a local udp socket with the given ips,
+// sending the queried names to `names` if it isn't nil.
+func serveDNS(t *testing.T, names chan<- string, ips ...string) net.PacketConn {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			query := buf[:n]
+
+			// find the end of the question section (qname, qtype, qclass).
+			end := 12
+			var name string
+			for query[end] != 0 {
+				name += string(query[end+1:end+1+int(query[end])]) + "."
+				end += int(query[end]) + 1
+			}
+			end++
+			if names != nil {
+				select {
+				case names <- name:
+				default:
+				}
+			}
+			qtype := binary.BigEndian.Uint16(query[end:])
+			end += 4
+
+			res := append([]byte{}, query[:end]...)
+			binary.BigEndian.PutUint16(res[2:], 0x8180)
+			binary.BigEndian.PutUint16(res[10:], 0)
+			if qtype != 1 {
+				binary.BigEndian.PutUint16(res[6:], 0)
+				conn.WriteTo(res, addr)
+				continue
+			}
+			binary.BigEndian.PutUint16(res[6:], uint16(len(ips)))
+			for _, ip := range ips {
+				res = append(res, 0xc0, 0x0c, 0, 1, 0, 1, 0, 0, 0, 60, 0, 4)
+				res = append(res, net.ParseIP(ip).To4()...)
+			}
+			conn.WriteTo(res, addr)
+		}
+	}()
+	return conn
+}
+
+func TestDNSProber(t *testing.T) {
+	assert := assert.New(t)
+
+	server := serveDNS(t, nil, "10.0.0.5", "10.0.0.6")
+	defer server.Close()
+
+	hostURL, err := url.Parse("dns://" + server.LocalAddr().String() + "/example.com?type=A&contains=10.0.0.5")
+	assert.Nil(err)
+	prober, err := NewProber(hostURL, &HostConfig{URL: hostURL.String()})
+	assert.Nil(err)
+
+	result := prober.Probe(time.Second)
+	assert.Nil(result.Err)
+	assert.Equal(StatusUp, result.Status)
+	assert.Equal("A 10.0.0.5,10.0.0.6", result.Details)
+
+	hostURL, err = url.Parse("dns://" + server.LocalAddr().String() + "/example.com?equals=10.0.0.5")
+	assert.Nil(err)
+	prober, err = NewProber(hostURL, &HostConfig{URL: hostURL.String()})
+	assert.Nil(err)
+
+	result = prober.Probe(time.Second)
+	assert.NotNil(result.Err)
+	assert.Equal(StatusDown, result.Status)
+}
+
+func TestDNSProberQueriesFullyQualifiedName(t *testing.T) {
+	assert := assert.New(t)
+
+	names := make(chan string, 16)
+	server := serveDNS(t, names, "10.0.0.5")
+	defer server.Close()
+
+	hostURL, err := url.Parse("dns://" + server.LocalAddr().String() + "/db?contains=10.0.0.5")
+	assert.Nil(err)
+	prober, err := NewProber(hostURL, &HostConfig{URL: hostURL.String()})
+	assert.Nil(err)
+
+	result := prober.Probe(time.Second)
+	assert.Nil(result.Err)
+	assert.Equal("db.", <-names, "the search domains should not be appended")
+	for len(names) > 0 {
+		assert.Equal("db.", <-names)
+	}
+}
+
+func TestDNSProberIgnoresHostsFile(t *testing.T) {
+	assert := assert.New(t)
+
+	names := make(chan string, 16)
+	server := serveDNS(t, names, "10.0.0.5")
+	defer server.Close()
+
+	// localhost is in /etc/hosts, which must not answer for the resolver.
+	hostURL, err := url.Parse("dns://" + server.LocalAddr().String() + "/localhost")
+	assert.Nil(err)
+	prober, err := NewProber(hostURL, &HostConfig{URL: hostURL.String()})
+	assert.Nil(err)
+
+	result := prober.Probe(time.Second)
+	assert.Nil(result.Err)
+	assert.Equal("A 10.0.0.5", result.Details)
+	assert.Equal("localhost.", <-names)
+}
+
+func TestParseDNSResponse(t *testing.T) {
+	assert := assert.New(t)
+
+	query, err := newDNSQuery("www.example.com", dnsTypeCNAME)
+	assert.Nil(err)
+	response := append([]byte{}, query...)
+	binary.BigEndian.PutUint16(response[2:], dnsFlagResponse|dnsFlagRecursion)
+	binary.BigEndian.PutUint16(response[6:], 3)
+	// www.example.com CNAME edge.example.com, compressed against the question.
+	response = append(response, 0xc0, 0x0c, 0, 5, 0, 1, 0, 0, 0, 60, 0, 7, 4, 'e', 'd', 'g', 'e', 0xc0, 0x10)
+	// edge.example.com CNAME lb.example.net.
+	response = append(response, 0xc0, 45, 0, 5, 0, 1, 0, 0, 0, 60, 0, 16, 2, 'l', 'b', 7, 'e', 'x', 'a', 'm', 'p', 'l', 'e', 3, 'n', 'e', 't', 0)
+	// example.com TXT "v=spf1" "-all".
+	response = append(response, 0xc0, 0x10, 0, 16, 0, 1, 0, 0, 0, 60, 0, 13, 6, 'v', '=', 's', 'p', 'f', '1', 5, ' ', '-', 'a', 'l', 'l')
+
+	records, err := parseDNSResponse(response)
+	assert.Nil(err)
+	assert.Len(records, 3)
+	assert.Equal("www.example.com.", records[0].name)
+	assert.Equal("edge.example.com.", records[0].value)
+	assert.Equal("edge.example.com.", records[1].name)
+	assert.Equal("lb.example.net.", records[1].value)
+	assert.Equal("v=spf1 -all", records[2].value)
+
+	target, found := findDNSRecord(records, "edge.example.com", dnsTypeCNAME)
+	assert.True(found)
+	assert.Equal("lb.example.net.", target)
+
+	binary.BigEndian.PutUint16(response[2:], dnsFlagResponse|3)
+	_, err = parseDNSResponse(response)
+	assert.NotNil(err)
+	assert.Equal("no such host", err.Error())
+}
+
+func TestDNSProberCheck(t *testing.T) {
+	assert := assert.New(t)
+
+	prober := &DNSProber{name: "www.example.com", recordType: "CNAME", equals: []string{"LB.example.com"}}
+	assert.Nil(prober.check([]string{"lb.example.com"}))
+	assert.NotNil(prober.check([]string{"other.example.com"}))
+
+	prober = &DNSProber{name: "example.com", recordType: "A", contains: []string{"10.0.0.5"}}
+	assert.Nil(prober.check([]string{"10.0.0.1", "10.0.0.5"}))
+	assert.NotNil(prober.check([]string{"10.0.0.1"}))
+}
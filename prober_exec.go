@@ -0,0 +1,213 @@
+package health
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// ExecWaitDelay is how long to wait for a plugin's output to close after it
+// exits or times out, for plugins that leave a child process holding it open.
+const ExecWaitDelay = time.Second
+
+// Nagios plugin exit codes.
+const (
+	NagiosOK       = 0
+	NagiosWarning  = 1
+	NagiosCritical = 2
+	NagiosUnknown  = 3
+)
+
+// NewExecProber returns a new prober that runs a nagios compatible plugin,
+// for urls of the form `exec:///path/to/check_thing -w 10% -c 5%`.
+func NewExecProber(hostURL *url.URL, config *HostConfig) (Prober, error) {
+	command := hostURL.Opaque
+	if len(command) == 0 {
+		command = hostURL.Path
+	}
+	args, err := SplitCommandLine(strings.TrimPrefix(command, "//"))
+	if err != nil {
+		return nil, err
+	}
+	if len(args) == 0 {
+		return nil, fmt.Errorf("exec host must include a command: %s", hostURL.String())
+	}
+	return &ExecProber{command: args[0], args: args[1:]}, nil
+}
+
+// ExecProber checks a host by running a local command that follows the nagios
+// plugin conventions; exit codes 0, 1, 2 and 3 are up, warning, down and
+// unknown, the first line of output is the status text, and anything after a
+// `|` is performance data.
+type ExecProber struct {
+	command string
+	args    []string
+}
+
+// Probe runs the command and maps its exit code and output onto a result.
+func (ep *ExecProber) Probe(timeout time.Duration) ProbeResult {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var stdout bytes.Buffer
+	cmd := exec.CommandContext(ctx, ep.command, ep.args...)
+	cmd.Stdout = &stdout
+	cmd.WaitDelay = ExecWaitDelay
+
+	begin := time.Now()
+	err := cmd.Run()
+	elapsed := time.Now().Sub(begin)
+	if errors.Is(err, exec.ErrWaitDelay) {
+		// the plugin exited cleanly, and only a child it left behind is holding the output open.
+		err = nil
+	}
+
+	if err != nil && ctx.Err() == context.DeadlineExceeded {
+		return NewProbeResult(elapsed, fmt.Errorf("%s: timed out after %v", ep.command, timeout))
+	}
+
+	exitCode := 0
+	if err != nil {
+		exitErr, isExitErr := err.(*exec.ExitError)
+		if !isExitErr {
+			return NewProbeResult(elapsed, err)
+		}
+		exitCode = exitErr.ExitCode()
+	}
+
+	text, metrics := ParseNagiosOutput(stdout.String())
+	if len(text) == 0 {
+		text = fmt.Sprintf("%s exited with %d", ep.command, exitCode)
+	}
+
+	result := ProbeResult{Elapsed: elapsed, Details: text, Metrics: metrics}
+	switch exitCode {
+	case NagiosOK:
+		result.Status = StatusUp
+	case NagiosWarning:
+		result.Status = StatusWarn
+	case NagiosCritical:
+		result.Status = StatusDown
+		result.Err = fmt.Errorf("%s", text)
+	default:
+		result.Status = StatusUnknown
+		result.Err = fmt.Errorf("%s", text)
+	}
+	return result
+}
+
+// ParseNagiosOutput returns the status text from the first line of plugin
+// output and the performance data from after the `|` on the first line and
+// after the first `|` in any following lines.
+func ParseNagiosOutput(output string) (string, []Metric) {
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+
+	var text string
+	var perfdata []string
+	pieces := strings.SplitN(lines[0], "|", 2)
+	text = strings.TrimSpace(pieces[0])
+	if len(pieces) == 2 {
+		perfdata = append(perfdata, pieces[1])
+	}
+
+	var inPerfdata bool
+	for _, line := range lines[1:] {
+		if inPerfdata {
+			perfdata = append(perfdata, line)
+			continue
+		}
+		if index := strings.Index(line, "|"); index >= 0 {
+			inPerfdata = true
+			perfdata = append(perfdata, line[index+1:])
+		}
+	}
+
+	var metrics []Metric
+	for _, data := range perfdata {
+		metrics = append(metrics, ParsePerfdata(data)...)
+	}
+	return text, metrics
+}
+
+// ParsePerfdata parses nagios performance data like
+// `'/ free'=2643MB;5948;5958;0;5968 load1=0.5;;;0`, ignoring malformed entries.
+func ParsePerfdata(perfdata string) []Metric {
+	fields, err := SplitCommandLine(perfdata)
+	if err != nil {
+		return nil
+	}
+
+	var metrics []Metric
+	for _, field := range fields {
+		pieces := strings.SplitN(field, "=", 2)
+		if len(pieces) != 2 || len(pieces[0]) == 0 {
+			continue
+		}
+		value := strings.SplitN(pieces[1], ";", 2)[0]
+		numberEnd := strings.IndexFunc(value, func(r rune) bool {
+			return !(unicode.IsDigit(r) || r == '.' || r == '-' || r == '+' || r == 'e' || r == 'E')
+		})
+		number, unit := value, ""
+		if numberEnd >= 0 {
+			number, unit = value[:numberEnd], value[numberEnd:]
+		}
+		parsed, err := strconv.ParseFloat(number, 64)
+		if err != nil {
+			continue
+		}
+		metrics = append(metrics, Metric{Name: pieces[0], Value: parsed, Unit: unit})
+	}
+	return metrics
+}
+
+// SplitCommandLine splits a command line on whitespace, honoring single and
+// double quotes and backslash escapes.
+func SplitCommandLine(line string) ([]string, error) {
+	var args []string
+	var current strings.Builder
+	var quote rune
+	var inArg, escaped bool
+
+	for _, c := range line {
+		switch {
+		case escaped:
+			current.WriteRune(c)
+			escaped = false
+		case c == '\\' && quote != '\'':
+			escaped = true
+			inArg = true
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			} else {
+				current.WriteRune(c)
+			}
+		case c == '\'' || c == '"':
+			quote = c
+			inArg = true
+		case unicode.IsSpace(c):
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+		default:
+			current.WriteRune(c)
+			inArg = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated quote in %q", line)
+	}
+	if inArg {
+		args = append(args, current.String())
+	}
+	return args, nil
+}
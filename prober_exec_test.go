@@ -0,0 +1,123 @@
+package health
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/blendlabs/go-assert"
+)
+
+func TestSplitCommandLine(t *testing.T) {
+	assert := assert.New(t)
+
+	args, err := SplitCommandLine(`/usr/lib/nagios/plugins/check_disk -w 10% -p "/var/lib/my data" 'it''s' a\ b`)
+	assert.Nil(err)
+	assert.Equal([]string{"/usr/lib/nagios/plugins/check_disk", "-w", "10%", "-p", "/var/lib/my data", "its", "a b"}, args)
+
+	_, err = SplitCommandLine(`check "unterminated`)
+	assert.NotNil(err)
+}
+
+func TestParseNagiosOutput(t *testing.T) {
+	assert := assert.New(t)
+
+	text, metrics := ParseNagiosOutput("DISK OK - free space: / 3326 MB (56%); | /=2643MB;5948;5958;0;5968\n/ 15272 MB (77%);\n/boot 68 MB (69%);\n/home 69357 MB (27%);| /boot=68MB;88;93;0;98\n'/home data'=69357MB;253404;253409;0;253414\n")
+	assert.Equal("DISK OK - free space: / 3326 MB (56%);", text)
+	assert.Equal([]Metric{
+		{Name: "/", Value: 2643, Unit: "MB"},
+		{Name: "/boot", Value: 68, Unit: "MB"},
+		{Name: "/home data", Value: 69357, Unit: "MB"},
+	}, metrics)
+
+	text, metrics = ParseNagiosOutput("LOAD OK")
+	assert.Equal("LOAD OK", text)
+	assert.Empty(metrics)
+}
+
+func TestExecProber(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "health-exec")
+	assert.Nil(err)
+	defer os.RemoveAll(dir)
+
+	plugin := filepath.Join(dir, "check_thing")
+	err = ioutil.WriteFile(plugin, []byte("#!/bin/sh\necho \"THING $1 - usage at $2 | usage=$2;80;95;0;100\"\nexit $3\n"), 0755)
+	assert.Nil(err)
+
+	probe := func(rawURL string) ProbeResult {
+		hostURL, err := ParseHostURL(rawURL)
+		assert.Nil(err)
+		prober, err := NewProber(hostURL, &HostConfig{URL: rawURL})
+		assert.Nil(err)
+		return prober.Probe(time.Second)
+	}
+
+	result := probe("exec://" + plugin + " OK 50% 0")
+	assert.Nil(result.Err)
+	assert.Equal(StatusUp, result.Status)
+	assert.Equal("THING OK - usage at 50%", result.Details)
+	assert.Equal([]Metric{{Name: "usage", Value: 50, Unit: "%"}}, result.Metrics)
+
+	result = probe("exec://" + plugin + " WARNING 85% 1")
+	assert.Nil(result.Err)
+	assert.Equal(StatusWarn, result.Status)
+
+	result = probe("exec://" + plugin + " CRITICAL 99% 2")
+	assert.NotNil(result.Err)
+	assert.Equal(StatusDown, result.Status)
+	assert.Equal("THING CRITICAL - usage at 99%", result.Err.Error())
+
+	result = probe("exec://" + plugin + " UNKNOWN 0% 3")
+	assert.NotNil(result.Err)
+	assert.Equal(StatusUnknown, result.Status)
+
+	result = probe("exec://" + filepath.Join(dir, "missing"))
+	assert.NotNil(result.Err)
+	assert.Equal(StatusDown, result.Status)
+}
+
+func TestExecProberLingeringChild(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "health-exec")
+	assert.Nil(err)
+	defer os.RemoveAll(dir)
+
+	// the backgrounded sleep inherits stdout and keeps it open after the plugin exits.
+	plugin := filepath.Join(dir, "check_wrapper")
+	err = ioutil.WriteFile(plugin, []byte("#!/bin/sh\nsleep 3 &\necho \"WRAPPER OK\"\nsleep $1\n"), 0755)
+	assert.Nil(err)
+
+	probe := func(rawURL string) ProbeResult {
+		hostURL, err := ParseHostURL(rawURL)
+		assert.Nil(err)
+		prober, err := NewProber(hostURL, &HostConfig{URL: rawURL})
+		assert.Nil(err)
+		return prober.Probe(500 * time.Millisecond)
+	}
+
+	begin := time.Now()
+	result := probe("exec://" + plugin + " 0")
+	assert.Nil(result.Err)
+	assert.Equal("WRAPPER OK", result.Details)
+	assert.True(time.Now().Sub(begin) < 5*time.Second)
+
+	begin = time.Now()
+	result = probe("exec://" + plugin + " 3")
+	assert.NotNil(result.Err)
+	assert.Contains("timed out", result.Err.Error())
+	assert.True(time.Now().Sub(begin) < 5*time.Second)
+}
+
+func TestParseHostURLExec(t *testing.T) {
+	assert := assert.New(t)
+
+	hostURL, err := ParseHostURL("exec:///usr/lib/nagios/plugins/check_disk -w 10%")
+	assert.Nil(err)
+	assert.Equal("exec", hostURL.Scheme)
+	assert.Equal("exec:///usr/lib/nagios/plugins/check_disk -w 10%", hostURL.String())
+}
@@ -0,0 +1,135 @@
+package health
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// NewFileProber returns a new file freshness prober for urls of the form
+// `file:///backups/db-*.sql.gz?maxAge=26h&minSize=1MB`. The path can be a glob,
+// in which case the most recently modified match is checked.
+func NewFileProber(hostURL *url.URL, config *HostConfig) (Prober, error) {
+	if len(hostURL.Host) > 0 {
+		return nil, fmt.Errorf("file host must be an absolute path, like `file:///%s%s`: %s", hostURL.Host, hostURL.Path, hostURL.String())
+	}
+	if len(hostURL.Path) == 0 {
+		return nil, fmt.Errorf("file host must include a path: %s", hostURL.String())
+	}
+	if _, err := filepath.Match(hostURL.Path, ""); err != nil {
+		return nil, fmt.Errorf("invalid file glob %q: %v", hostURL.Path, err)
+	}
+
+	prober := &FileProber{pattern: hostURL.Path}
+	query := hostURL.Query()
+	if maxAge := query.Get("maxAge"); len(maxAge) > 0 {
+		value, err := time.ParseDuration(maxAge)
+		if err != nil || value <= 0 {
+			return nil, fmt.Errorf("invalid file `maxAge`: %q", maxAge)
+		}
+		prober.maxAge = value
+	}
+	if minSize := query.Get("minSize"); len(minSize) > 0 {
+		value, err := ParseByteSize(minSize)
+		if err != nil {
+			return nil, fmt.Errorf("invalid file `minSize`: %v", err)
+		}
+		prober.minSize = value
+	}
+	return prober, nil
+}
+
+// FileProber checks that a file, or the newest file matching a glob, exists,
+// was modified within a maximum age, and is at least a minimum size.
+type FileProber struct {
+	pattern string
+	maxAge  time.Duration
+	minSize int64
+}
+
+// Probe finds the newest matching file and checks its age and size.
+func (fp *FileProber) Probe(timeout time.Duration) ProbeResult {
+	begin := time.Now()
+	matches, err := filepath.Glob(fp.pattern)
+	if err != nil {
+		return NewProbeResult(time.Now().Sub(begin), err)
+	}
+
+	var newestPath string
+	var newest os.FileInfo
+	for _, path := range matches {
+		info, err := os.Stat(path)
+		if err != nil || info.IsDir() {
+			continue
+		}
+		if newest == nil || info.ModTime().After(newest.ModTime()) {
+			newestPath, newest = path, info
+		}
+	}
+	elapsed := time.Now().Sub(begin)
+	if newest == nil {
+		return NewProbeResult(elapsed, fmt.Errorf("no file matches %s", fp.pattern))
+	}
+
+	age := time.Now().Sub(newest.ModTime())
+	if age < 0 {
+		age = 0
+	}
+	roundedAge := FormatRoundedDuration(age, time.Second)
+
+	var result ProbeResult
+	switch {
+	case fp.maxAge > 0 && age > fp.maxAge:
+		result = NewProbeResult(elapsed, fmt.Errorf("%s is %s old, expected under %s", newestPath, roundedAge, FormatDuration(fp.maxAge)))
+	case newest.Size() < fp.minSize:
+		result = NewProbeResult(elapsed, fmt.Errorf("%s is %s, expected at least %s", newestPath, FormatByteSize(newest.Size()), FormatByteSize(fp.minSize)))
+	default:
+		result = NewProbeResult(elapsed, nil)
+	}
+	result.Details = fmt.Sprintf("%s, %s old, %s", filepath.Base(newestPath), roundedAge, FormatByteSize(newest.Size()))
+	return result
+}
+
+var byteSizeUnits = []struct {
+	suffix string
+	size   int64
+}{
+	{"TB", 1 << 40},
+	{"GB", 1 << 30},
+	{"MB", 1 << 20},
+	{"KB", 1 << 10},
+	{"B", 1},
+}
+
+// ParseByteSize parses a size like `512`, `100KB` or `1.5GB`, in powers of 1024.
+func ParseByteSize(value string) (int64, error) {
+	trimmed := strings.ToUpper(strings.TrimSpace(value))
+	for _, unit := range byteSizeUnits {
+		if strings.HasSuffix(trimmed, unit.suffix) {
+			number, err := strconv.ParseFloat(strings.TrimSpace(strings.TrimSuffix(trimmed, unit.suffix)), 64)
+			if err != nil || number < 0 {
+				return 0, fmt.Errorf("invalid size %q", value)
+			}
+			return int64(number * float64(unit.size)), nil
+		}
+	}
+	number, err := strconv.ParseInt(trimmed, 10, 64)
+	if err != nil || number < 0 {
+		return 0, fmt.Errorf("invalid size %q", value)
+	}
+	return number, nil
+}
+
+// FormatByteSize formats a size in the largest unit it has at least one of, e.g. `1.5GB`.
+func FormatByteSize(size int64) string {
+	for _, unit := range byteSizeUnits {
+		if size >= unit.size {
+			return strconv.FormatFloat(round(float64(size)/float64(unit.size), 1), 'f', -1, 64) + unit.suffix
+		}
+	}
+	return "0B"
+}
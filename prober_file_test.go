@@ -0,0 +1,74 @@
+package health
+
+import (
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/blendlabs/go-assert"
+)
+
+func TestFileProber(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "health-file")
+	assert.Nil(err)
+	defer os.RemoveAll(dir)
+
+	result := probeHost(assert, HostConfig{URL: "file://" + dir + "/db-*.sql.gz?maxAge=26h"})
+	assert.Equal(StatusDown, result.Status)
+	assert.Contains("no file matches", result.Err.Error())
+
+	older := filepath.Join(dir, "db-1.sql.gz")
+	assert.Nil(ioutil.WriteFile(older, make([]byte, 4096), 0644))
+	assert.Nil(os.Chtimes(older, time.Now().Add(-50*time.Hour), time.Now().Add(-50*time.Hour)))
+
+	result = probeHost(assert, HostConfig{URL: "file://" + dir + "/db-*.sql.gz?maxAge=26h"})
+	assert.Equal(StatusDown, result.Status)
+	assert.Equal(older+" is 50h old, expected under 26h", result.Err.Error())
+
+	newer := filepath.Join(dir, "db-2.sql.gz")
+	assert.Nil(ioutil.WriteFile(newer, make([]byte, 1024), 0644))
+	assert.Nil(os.Chtimes(newer, time.Now().Add(-2*time.Hour), time.Now().Add(-2*time.Hour)))
+
+	result = probeHost(assert, HostConfig{URL: "file://" + dir + "/db-*.sql.gz?maxAge=26h"})
+	assert.Nil(result.Err)
+	assert.Equal("db-2.sql.gz, 2h old, 1KB", result.Details)
+
+	result = probeHost(assert, HostConfig{URL: "file://" + dir + "/db-*.sql.gz?maxAge=26h&minSize=2KB"})
+	assert.Equal(StatusDown, result.Status)
+	assert.Equal(newer+" is 1KB, expected at least 2KB", result.Err.Error())
+
+	result = probeHost(assert, HostConfig{URL: "file://" + older + "?minSize=2KB"})
+	assert.Nil(result.Err)
+}
+
+func TestFileProberValidation(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, rawURL := range []string{"file://", "file:///tmp/[?maxAge=1h", "file:///tmp/x?maxAge=yesterday", "file:///tmp/x?minSize=big", "file://backups/x"} {
+		hostURL, err := url.Parse(rawURL)
+		assert.Nil(err)
+		_, err = NewProber(hostURL, &HostConfig{URL: rawURL})
+		assert.NotNil(err)
+	}
+}
+
+func TestByteSize(t *testing.T) {
+	assert := assert.New(t)
+
+	for value, expected := range map[string]int64{"512": 512, "100KB": 102400, "1.5gb": 1610612736, "2 MB": 2097152, "0": 0} {
+		parsed, err := ParseByteSize(value)
+		assert.Nil(err)
+		assert.Equal(expected, parsed)
+	}
+	_, err := ParseByteSize("-1MB")
+	assert.NotNil(err)
+
+	assert.Equal("1.5GB", FormatByteSize(1610612736))
+	assert.Equal("512B", FormatByteSize(512))
+	assert.Equal("0B", FormatByteSize(0))
+}
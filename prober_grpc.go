@@ -0,0 +1,178 @@
+package health
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// GRPCHealthCheckPath is the method path of the standard grpc health check.
+	GRPCHealthCheckPath = "/grpc.health.v1.Health/Check"
+)
+
+// Serving statuses returned by `grpc.health.v1.Health/Check`.
+const (
+	GRPCServingStatusUnknown        = 0
+	GRPCServingStatusServing        = 1
+	GRPCServingStatusNotServing     = 2
+	GRPCServingStatusServiceUnknown = 3
+)
+
+// NewGRPCProber returns a new grpc health check prober for urls of the form
+// `grpc://host:port/service`, or `grpcs://` for servers that use tls.
+func NewGRPCProber(hostURL *url.URL, config *HostConfig) (Prober, error) {
+	if len(hostURL.Port()) == 0 {
+		return nil, fmt.Errorf("grpc host must include a port: %s", hostURL.String())
+	}
+
+	scheme := "http"
+	protocols := new(http.Protocols)
+	if strings.EqualFold(hostURL.Scheme, "grpcs") {
+		scheme = "https"
+		protocols.SetHTTP2(true)
+	} else {
+		protocols.SetUnencryptedHTTP2(true)
+	}
+
+	return &GRPCProber{
+		url:       fmt.Sprintf("%s://%s%s", scheme, hostURL.Host, GRPCHealthCheckPath),
+		service:   strings.TrimPrefix(hostURL.Path, "/"),
+		transport: config.pinTransport(&http.Transport{Protocols: protocols}),
+	}, nil
+}
+
+// GRPCProber checks a host with the grpc health checking protocol.
+type GRPCProber struct {
+	url       string
+	service   string
+	transport *http.Transport
+}
+
+// Probe calls `grpc.health.v1.Health/Check` and maps the serving status onto a host status.
+func (gp *GRPCProber) Probe(timeout time.Duration) ProbeResult {
+	req, err := http.NewRequest("POST", gp.url, bytes.NewReader(gp.request()))
+	if err != nil {
+		return NewProbeResult(0, err)
+	}
+	req.Header.Set("Content-Type", "application/grpc")
+	req.Header.Set("TE", "trailers")
+
+	client := &http.Client{Transport: gp.transport, Timeout: timeout}
+
+	begin := time.Now()
+	res, err := client.Do(req)
+	if err != nil {
+		return NewProbeResult(time.Now().Sub(begin), err)
+	}
+	defer res.Body.Close()
+	body, err := ioutil.ReadAll(res.Body)
+	elapsed := time.Now().Sub(begin)
+	if err != nil {
+		return NewProbeResult(elapsed, err)
+	}
+
+	if res.StatusCode != http.StatusOK {
+		return NewProbeResult(elapsed, fmt.Errorf("grpc: non-200 returned from endpoint: %d", res.StatusCode))
+	}
+
+	// trailers-only responses put the status in the headers.
+	grpcStatus := res.Trailer.Get("Grpc-Status")
+	grpcMessage := res.Trailer.Get("Grpc-Message")
+	if len(grpcStatus) == 0 {
+		grpcStatus = res.Header.Get("Grpc-Status")
+		grpcMessage = res.Header.Get("Grpc-Message")
+	}
+	if grpcStatus != "0" {
+		return NewProbeResult(elapsed, fmt.Errorf("grpc: status %s: %s", grpcStatus, grpcMessage))
+	}
+
+	servingStatus, err := parseGRPCHealthCheckResponse(body)
+	if err != nil {
+		return NewProbeResult(elapsed, err)
+	}
+
+	switch servingStatus {
+	case GRPCServingStatusServing:
+		return NewProbeResult(elapsed, nil)
+	case GRPCServingStatusNotServing:
+		return NewProbeResult(elapsed, fmt.Errorf("grpc: service %q is NOT_SERVING", gp.service))
+	case GRPCServingStatusServiceUnknown:
+		return NewProbeResult(elapsed, fmt.Errorf("grpc: service %q is SERVICE_UNKNOWN", gp.service))
+	}
+	return ProbeResult{
+		Elapsed: elapsed,
+		Status:  StatusUnknown,
+		Err:     fmt.Errorf("grpc: service %q is UNKNOWN", gp.service),
+	}
+}
+
+// request returns a length-prefixed `HealthCheckRequest{service}` message.
+func (gp *GRPCProber) request() []byte {
+	var message []byte
+	if len(gp.service) > 0 {
+		message = append(message, 0x0a) // field 1, length delimited
+		message = binary.AppendUvarint(message, uint64(len(gp.service)))
+		message = append(message, gp.service...)
+	}
+
+	frame := make([]byte, 5, 5+len(message))
+	binary.BigEndian.PutUint32(frame[1:], uint32(len(message)))
+	return append(frame, message...)
+}
+
+// parseGRPCHealthCheckResponse reads the status from a length-prefixed
+// `HealthCheckResponse` message.
+func parseGRPCHealthCheckResponse(body []byte) (uint64, error) {
+	if len(body) < 5 {
+		return 0, fmt.Errorf("grpc: short response message")
+	}
+	if body[0] != 0 {
+		return 0, fmt.Errorf("grpc: compressed responses are not supported")
+	}
+	length := binary.BigEndian.Uint32(body[1:5])
+	if uint32(len(body)-5) < length {
+		return 0, fmt.Errorf("grpc: truncated response message")
+	}
+	message := body[5 : 5+length]
+
+	var status uint64
+	for len(message) > 0 {
+		tag, n := binary.Uvarint(message)
+		if n <= 0 {
+			return 0, fmt.Errorf("grpc: invalid response message")
+		}
+		message = message[n:]
+
+		var value uint64
+		switch tag & 0x7 {
+		case 0:
+			value, n = binary.Uvarint(message)
+		case 1:
+			n = 8
+		case 2:
+			value, n = binary.Uvarint(message)
+			if n > 0 {
+				n += int(value)
+			}
+		case 5:
+			n = 4
+		default:
+			return 0, fmt.Errorf("grpc: invalid response message")
+		}
+		if n <= 0 || n > len(message) {
+			return 0, fmt.Errorf("grpc: invalid response message")
+		}
+		message = message[n:]
+
+		if tag == 0x08 { // field 1, varint
+			status = value
+		}
+	}
+	return status, nil
+}
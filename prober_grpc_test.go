@@ -0,0 +1,81 @@
+package health
+
+import (
+	"encoding/binary"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/blendlabs/go-assert"
+)
+
+// serveGRPCHealth serves `grpc.health.v1.Health/Check` over h2c, returning the
+// serving status registered for the requested service.
+func serveGRPCHealth(statuses map[string]byte) *httptest.Server {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		var service string
+		if len(body) > 7 {
+			service = string(body[7:])
+		}
+
+		rw.Header().Set("Content-Type", "application/grpc")
+		rw.Header().Set("Trailer", "Grpc-Status, Grpc-Message")
+		status, hasStatus := statuses[service]
+		if !hasStatus {
+			rw.Header().Set("Grpc-Status", "5")
+			rw.Header().Set("Grpc-Message", "unknown service")
+			return
+		}
+
+		message := []byte{0, 0, 0, 0, 2, 0x08, status}
+		binary.BigEndian.PutUint32(message[1:], 2)
+		rw.Write(message)
+		rw.Header().Set("Grpc-Status", "0")
+	}))
+	server.Config.Protocols = new(http.Protocols)
+	server.Config.Protocols.SetUnencryptedHTTP2(true)
+	server.Start()
+	return server
+}
+
+func TestGRPCProber(t *testing.T) {
+	assert := assert.New(t)
+
+	server := serveGRPCHealth(map[string]byte{
+		"":        GRPCServingStatusServing,
+		"orders":  GRPCServingStatusNotServing,
+		"billing": GRPCServingStatusUnknown,
+	})
+	defer server.Close()
+
+	serverURL, err := url.Parse(server.URL)
+	assert.Nil(err)
+
+	probe := func(service string) ProbeResult {
+		hostURL, err := url.Parse("grpc://" + serverURL.Host + "/" + service)
+		assert.Nil(err)
+		prober, err := NewProber(hostURL, &HostConfig{URL: hostURL.String()})
+		assert.Nil(err)
+		return prober.Probe(time.Second)
+	}
+
+	result := probe("")
+	assert.Nil(result.Err)
+	assert.Equal(StatusUp, result.Status)
+
+	result = probe("orders")
+	assert.NotNil(result.Err)
+	assert.Equal(StatusDown, result.Status)
+
+	result = probe("billing")
+	assert.NotNil(result.Err)
+	assert.Equal(StatusUnknown, result.Status)
+
+	result = probe("missing")
+	assert.NotNil(result.Err)
+	assert.Equal(StatusDown, result.Status)
+}
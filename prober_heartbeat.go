@@ -0,0 +1,105 @@
+package health
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// NewHeartbeatProber returns a new passive prober for urls of the form
+// `heartbeat://name?period=1h&grace=5m[&stateFile=/path/to/runs.jsonl]`. The
+// name is registered with the heartbeats of the `Checks` the host belongs to, so
+// that `/heartbeat/<name>` is accepted, and runs appended to `stateFile` by
+// `health run` count as heartbeats. A host created on its own gets its own store.
+func NewHeartbeatProber(hostURL *url.URL, config *HostConfig) (Prober, error) {
+	name := hostURL.Host + hostURL.Path
+	if len(name) == 0 {
+		return nil, fmt.Errorf("heartbeat host must include a name: %s", hostURL.String())
+	}
+
+	query := hostURL.Query()
+	period, err := time.ParseDuration(query.Get("period"))
+	if err != nil || period <= 0 {
+		return nil, fmt.Errorf("heartbeat host must include a `period`: %s", hostURL.String())
+	}
+	var grace time.Duration
+	if value := query.Get("grace"); len(value) > 0 {
+		grace, err = time.ParseDuration(value)
+		if err != nil || grace < 0 {
+			return nil, fmt.Errorf("invalid heartbeat `grace`: %q", value)
+		}
+	}
+
+	heartbeats := config.heartbeats
+	if heartbeats == nil {
+		heartbeats = NewHeartbeats()
+	}
+	heartbeats.Register(name)
+	return &HeartbeatProber{
+		name:       name,
+		period:     period,
+		grace:      grace,
+		stateFile:  query.Get("stateFile"),
+		heartbeats: heartbeats,
+		startedAt:  time.Now(),
+	}, nil
+}
+
+// HeartbeatProber checks a host that reports in to us; the host is down if no
+// heartbeat has arrived within its period plus grace.
+type HeartbeatProber struct {
+	name       string
+	period     time.Duration
+	grace      time.Duration
+	stateFile  string
+	heartbeats *Heartbeats
+	startedAt  time.Time
+}
+
+// LastSeen returns when the last heartbeat arrived.
+func (hp *HeartbeatProber) LastSeen() (time.Time, bool) {
+	return hp.heartbeats.LastSeen(hp.name)
+}
+
+// Probe checks the age of the last heartbeat, and the exit code of the last
+// job run if there is one. Until the first heartbeat arrives the status is
+// unknown, for up to a period plus grace after startup.
+func (hp *HeartbeatProber) Probe(timeout time.Duration) ProbeResult {
+	if len(hp.stateFile) > 0 {
+		if err := hp.readStateFile(); err != nil {
+			return ProbeResult{Status: StatusUnknown, Err: err}
+		}
+	}
+
+	deadline := hp.period + hp.grace
+	lastSeen, seen := hp.LastSeen()
+	if !seen {
+		if waiting := time.Now().Sub(hp.startedAt); waiting > deadline {
+			return NewProbeResult(0, fmt.Errorf("no heartbeat from %s in %s", hp.name, FormatDuration(RoundDuration(waiting, time.Second))))
+		}
+		return ProbeResult{Status: StatusUnknown, Details: "waiting for first heartbeat"}
+	}
+	if age := time.Now().Sub(lastSeen); age > deadline {
+		return NewProbeResult(0, fmt.Errorf("last heartbeat from %s was %s ago, expected every %s", hp.name, FormatDuration(RoundDuration(age, time.Second)), FormatDuration(hp.period)))
+	}
+
+	run, hasRun := hp.heartbeats.LastRun(hp.name)
+	if !hasRun {
+		return NewProbeResult(0, nil)
+	}
+	result := NewProbeResult(0, run.Err())
+	result.Details = fmt.Sprintf("last run: %v, exit %d", run.Duration.Round(time.Millisecond), run.ExitCode)
+	return result
+}
+
+// readStateFile records the job's last run from the state file, if it is newer than the last heartbeat.
+func (hp *HeartbeatProber) readStateFile() error {
+	run, found, err := LastJobRun(hp.stateFile, hp.name)
+	if err != nil || !found {
+		return err
+	}
+	if lastSeen, seen := hp.LastSeen(); !seen || run.FinishedAt().After(lastSeen) {
+		hp.heartbeats.Record(run, run.FinishedAt())
+	}
+	return nil
+}
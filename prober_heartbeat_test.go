@@ -0,0 +1,96 @@
+package health
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/blendlabs/go-assert"
+)
+
+func TestHeartbeatProber(t *testing.T) {
+	assert := assert.New(t)
+
+	hostURL, err := url.Parse("heartbeat://etl-pipeline?period=1h&grace=5m")
+	assert.Nil(err)
+	prober, err := NewProber(hostURL, &HostConfig{URL: hostURL.String()})
+	assert.Nil(err)
+	heartbeat := prober.(*HeartbeatProber)
+
+	result := prober.Probe(time.Second)
+	assert.Equal(StatusUnknown, result.Status)
+	assert.Nil(result.Err)
+
+	heartbeat.startedAt = time.Now().Add(-2 * time.Hour)
+	result = prober.Probe(time.Second)
+	assert.Equal(StatusDown, result.Status)
+	assert.Contains("no heartbeat from etl-pipeline", result.Err.Error())
+
+	assert.True(heartbeat.heartbeats.Beat("etl-pipeline", time.Now().Add(-30*time.Minute)))
+	result = prober.Probe(time.Second)
+	assert.Equal(StatusUp, result.Status)
+
+	heartbeat.heartbeats.Beat("etl-pipeline", time.Now().Add(-66*time.Minute))
+	result = prober.Probe(time.Second)
+	assert.Equal(StatusDown, result.Status)
+	assert.Equal("last heartbeat from etl-pipeline was 1h6m ago, expected every 1h", result.Err.Error())
+}
+
+func TestHeartbeatProberValidation(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, rawURL := range []string{"heartbeat://backup", "heartbeat://backup?period=soon", "heartbeat://?period=1h", "heartbeat://backup?period=1h&grace=-1m"} {
+		hostURL, err := url.Parse(rawURL)
+		assert.Nil(err)
+		_, err = NewProber(hostURL, &HostConfig{URL: rawURL})
+		assert.NotNil(err)
+	}
+}
+
+func TestHeartbeatHostStatus(t *testing.T) {
+	assert := assert.New(t)
+
+	host, err := NewHostFromConfig(&HostConfig{URL: "heartbeat://report-mailer?period=24h"}, time.Second, 16)
+	assert.Nil(err)
+	checks := &Checks{}
+	checks.Ping(host)
+
+	buf := bytes.NewBuffer(nil)
+	assert.Nil(host.WriteStatus(16, 0, buf))
+	assert.Contains("Last Seen", buf.String())
+	assert.Contains("never", buf.String())
+
+	host.prober.(*HeartbeatProber).heartbeats.Beat("report-mailer", time.Now().Add(-90*time.Second))
+	checks.Ping(host)
+	buf.Reset()
+	assert.Nil(host.WriteStatus(16, 0, buf))
+	assert.Contains("1m30s ago", buf.String())
+}
+
+func TestHeartbeatProberJobRuns(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "health-jobs")
+	assert.Nil(err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "runs.jsonl")
+
+	hostURL, err := url.Parse("heartbeat://log-rotate?period=1h&stateFile=" + url.QueryEscape(path))
+	assert.Nil(err)
+	prober, err := NewProber(hostURL, &HostConfig{URL: hostURL.String()})
+	assert.Nil(err)
+
+	assert.Nil(AppendJobRun(path, JobRun{Name: "log-rotate", StartedAt: time.Now().Add(-time.Minute), Duration: 1500 * time.Millisecond, ExitCode: 1, Output: "rotating\npermission denied"}))
+	result := prober.Probe(time.Second)
+	assert.Equal(StatusDown, result.Status)
+	assert.Equal("log-rotate exited with 1 after 1.5s: permission denied", result.Err.Error())
+
+	assert.Nil(AppendJobRun(path, JobRun{Name: "log-rotate", StartedAt: time.Now().Add(-time.Second), Duration: 500 * time.Millisecond}))
+	result = prober.Probe(time.Second)
+	assert.Equal(StatusUp, result.Status)
+	assert.Equal("last run: 500ms, exit 0", result.Details)
+}
@@ -0,0 +1,417 @@
+package health
+
+import (
+	"crypto/tls"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/blendlabs/go-request"
+)
+
+// NewHTTPProber returns a new http prober, or a transaction prober if the host has `steps`.
+func NewHTTPProber(hostURL *url.URL, config *HostConfig) (Prober, error) {
+	if len(config.Steps) > 0 {
+		return NewTransactionProber(hostURL, config)
+	}
+	return newHTTPProber(hostURL, config)
+}
+
+// newHTTPProber returns a new http prober for a single request.
+func newHTTPProber(hostURL *url.URL, config *HostConfig) (*HTTPProber, error) {
+	assertions, err := CompileBodyAssertions(config.Assertions)
+	if err != nil {
+		return nil, err
+	}
+	expectStatus, err := ParseStatusCodes(config.GetExpectStatus())
+	if err != nil {
+		return nil, err
+	}
+	if config.Freshness != nil {
+		if err := config.Freshness.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	var version *Extraction
+	if config.Version != nil {
+		compiled, err := Extraction{Name: "version", JSONPath: config.Version.JSONPath, Header: config.Version.Header, Regex: config.Version.Regex}.Compile()
+		if err != nil {
+			return nil, err
+		}
+		version = &compiled
+	}
+	var metricAssertions []*MetricAssertion
+	for _, expression := range config.MetricAssertions {
+		assertion, err := ParseMetricAssertion(expression)
+		if err != nil {
+			return nil, err
+		}
+		metricAssertions = append(metricAssertions, assertion)
+	}
+	body, err := config.GetBody()
+	if err != nil {
+		return nil, err
+	}
+	return &HTTPProber{
+		url:              hostURL,
+		method:           config.GetMethod(),
+		headers:          config.Headers,
+		body:             body,
+		basicAuth:        config.BasicAuth,
+		bearerToken:      config.BearerToken,
+		transport:        config.pinTransport(http.DefaultTransport.(*http.Transport).Clone()),
+		checkCertificate: config.CheckCertificate,
+		certWarningDays:  config.GetCertWarningDays(),
+		assertions:       assertions,
+		metricAssertions: metricAssertions,
+		freshness:        config.Freshness,
+		version:          version,
+		expectStatus:     expectStatus,
+		followRedirects:  config.ShouldFollowRedirects(),
+		maxRedirects:     config.GetMaxRedirects(),
+		expectFinalURL:   config.ExpectFinalURL,
+	}, nil
+}
+
+// HTTPProber checks a host with an http request.
+type HTTPProber struct {
+	url              *url.URL
+	method           string
+	headers          map[string]string
+	body             []byte
+	basicAuth        *BasicAuth
+	bearerToken      string
+	req              *request.Request
+	transport        *http.Transport
+	checkCertificate bool
+	certWarningDays  int
+	assertions       []BodyAssertion
+	metricAssertions []*MetricAssertion
+	freshness        *FreshnessConfig
+	version          *Extraction
+	expectStatus     StatusCodes
+	followRedirects  bool
+	maxRedirects     int
+	expectFinalURL   string
+}
+
+// ReportsVersion returns if the host has a `version` extractor.
+func (hp *HTTPProber) ReportsVersion() bool {
+	return hp.version != nil
+}
+
+func (hp *HTTPProber) ensureRequest() *request.Request {
+	if hp.req != nil {
+		return hp.req
+	}
+
+	req := request.New().
+		WithVerb(hp.method).
+		WithKeepAlives().
+		WithURL(hp.url.String())
+
+	for key, value := range hp.headers {
+		req.WithHeader(key, value)
+	}
+	if len(hp.body) > 0 {
+		req.WithPostBody(hp.body)
+	}
+	if hp.basicAuth != nil {
+		req.WithBasicAuth(hp.basicAuth.Username, hp.basicAuth.Password)
+	}
+	if len(hp.bearerToken) > 0 {
+		req.WithHeader("Authorization", "Bearer "+hp.bearerToken)
+	}
+
+	hp.req = req
+	return req
+}
+
+// checkRedirect enforces the redirect policy for the host.
+func (hp *HTTPProber) checkRedirect(req *http.Request, via []*http.Request) error {
+	if !hp.followRedirects {
+		return http.ErrUseLastResponse
+	}
+	if len(via) > hp.maxRedirects {
+		return fmt.Errorf("stopped after %d redirects", hp.maxRedirects)
+	}
+	return nil
+}
+
+// Probe issues the request and returns the elapsed time, the time spent in
+// each phase of the request and any errors.
+func (hp *HTTPProber) Probe(timeout time.Duration) ProbeResult {
+	req, err := hp.ensureRequest().Request()
+	if err != nil {
+		return NewProbeResult(0, err)
+	}
+	client := &http.Client{
+		Transport:     hp.transport,
+		Timeout:       timeout,
+		CheckRedirect: hp.checkRedirect,
+	}
+	trace := new(phaseTrace)
+	req = req.WithContext(httptrace.WithClientTrace(req.Context(), trace.ClientTrace()))
+
+	begin := time.Now()
+	res, err := client.Do(req)
+	if err != nil {
+		result := NewProbeResult(time.Now().Sub(begin), err)
+		result.Timings = trace.Timings(time.Now())
+		return result
+	}
+	defer res.Body.Close()
+	body, err := ioutil.ReadAll(res.Body)
+	end := time.Now()
+	elapsed := end.Sub(begin)
+
+	var result ProbeResult
+	if err != nil {
+		result = NewProbeResult(elapsed, err)
+	} else {
+		result = hp.check(res, body, elapsed)
+	}
+	result.Timings = trace.Timings(end)
+	return result
+}
+
+// check runs the configured checks against a response. A `application/health+json`
+// response also sets the status from its document, and a failing document is
+// reported ahead of the status code it was returned with.
+func (hp *HTTPProber) check(res *http.Response, body []byte, elapsed time.Duration) ProbeResult {
+	var healthResult *ProbeResult
+	if IsHealthJSON(res.Header) {
+		document, err := ParseHealthJSON(body)
+		if err != nil {
+			return NewProbeResult(elapsed, err)
+		}
+		result := document.Result()
+		result.Elapsed = elapsed
+		if result.Status == StatusDown {
+			return result
+		}
+		healthResult = &result
+	}
+
+	if !hp.expectStatus.Contains(res.StatusCode) {
+		return NewProbeResult(elapsed, fmt.Errorf("unexpected status code %d returned from endpoint, expected %s", res.StatusCode, hp.expectStatus))
+	}
+
+	if len(hp.expectFinalURL) > 0 {
+		if finalURL := res.Request.URL.String(); finalURL != hp.expectFinalURL {
+			return NewProbeResult(elapsed, fmt.Errorf("expected final url %s, got %s", hp.expectFinalURL, finalURL))
+		}
+	}
+
+	if err := CheckBodyAssertions(hp.assertions, body); err != nil {
+		return NewProbeResult(elapsed, err)
+	}
+
+	var metrics []Metric
+	if len(hp.metricAssertions) > 0 {
+		checked, err := CheckMetricAssertions(hp.metricAssertions, body)
+		if err != nil {
+			result := NewProbeResult(elapsed, err)
+			result.Metrics = checked
+			return result
+		}
+		metrics = checked
+	}
+
+	var freshness ProbeResult
+	if hp.freshness != nil {
+		freshness = hp.freshness.Check(res, body)
+		if freshness.Err != nil {
+			result := NewProbeResult(elapsed, freshness.Err)
+			result.Metrics = metrics
+			return result
+		}
+	}
+
+	result := NewProbeResult(elapsed, nil)
+	if healthResult != nil {
+		result = *healthResult
+	}
+	if hp.checkCertificate && res.TLS != nil {
+		certResult := CheckCertificates(res.TLS.PeerCertificates, hp.url.Hostname(), hp.certWarningDays)
+		certResult.Elapsed = elapsed
+		if healthResult != nil && certResult.Err == nil {
+			if healthResult.Status > certResult.Status {
+				certResult.Status = healthResult.Status
+			}
+			if len(healthResult.Details) > 0 {
+				certResult.Details = healthResult.Details + ", " + certResult.Details
+			}
+			certResult.Err = healthResult.Err
+		}
+		result = certResult
+	}
+	if hp.freshness != nil {
+		if freshness.Status > result.Status {
+			result.Status = freshness.Status
+		}
+		result.Details = strings.TrimPrefix(result.Details+", "+freshness.Details, ", ")
+	}
+	result.Metrics = metrics
+	if hp.version != nil {
+		// a host that doesn't report a version is left out of drift detection rather than failed.
+		result.Version, _ = hp.version.Extract(res, body)
+	}
+	return result
+}
+
+// phaseTrace records the time spent in each phase of an http request.
+// Phases are summed across redirects, and are zero when a kept-alive
+// connection is reused.
+type phaseTrace struct {
+	sync.Mutex
+	dnsStart     time.Time
+	connectStart time.Time
+	tlsStart     time.Time
+	wroteRequest time.Time
+	firstByte    time.Time
+
+	dns     time.Duration
+	connect time.Duration
+	tls     time.Duration
+	ttfb    time.Duration
+}
+
+// ClientTrace returns the hooks that populate the trace.
+func (pt *phaseTrace) ClientTrace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		DNSStart: func(_ httptrace.DNSStartInfo) {
+			pt.Lock()
+			defer pt.Unlock()
+			pt.dnsStart = time.Now()
+		},
+		DNSDone: func(_ httptrace.DNSDoneInfo) {
+			pt.Lock()
+			defer pt.Unlock()
+			pt.dns += time.Now().Sub(pt.dnsStart)
+		},
+		ConnectStart: func(_, _ string) {
+			pt.Lock()
+			defer pt.Unlock()
+			pt.connectStart = time.Now()
+		},
+		ConnectDone: func(_, _ string, _ error) {
+			pt.Lock()
+			defer pt.Unlock()
+			pt.connect += time.Now().Sub(pt.connectStart)
+		},
+		TLSHandshakeStart: func() {
+			pt.Lock()
+			defer pt.Unlock()
+			pt.tlsStart = time.Now()
+		},
+		TLSHandshakeDone: func(_ tls.ConnectionState, _ error) {
+			pt.Lock()
+			defer pt.Unlock()
+			pt.tls += time.Now().Sub(pt.tlsStart)
+		},
+		WroteRequest: func(_ httptrace.WroteRequestInfo) {
+			pt.Lock()
+			defer pt.Unlock()
+			pt.wroteRequest = time.Now()
+		},
+		GotFirstResponseByte: func() {
+			pt.Lock()
+			defer pt.Unlock()
+			pt.firstByte = time.Now()
+			pt.ttfb += pt.firstByte.Sub(pt.wroteRequest)
+		},
+	}
+}
+
+// Timings returns the phases of the request, with `end` marking when the body was read.
+func (pt *phaseTrace) Timings(end time.Time) []Timing {
+	pt.Lock()
+	defer pt.Unlock()
+
+	var transfer time.Duration
+	if !pt.firstByte.IsZero() {
+		transfer = end.Sub(pt.firstByte)
+	}
+	return []Timing{
+		{Name: "dns", Elapsed: pt.dns},
+		{Name: "connect", Elapsed: pt.connect},
+		{Name: "tls", Elapsed: pt.tls},
+		{Name: "ttfb", Elapsed: pt.ttfb},
+		{Name: "transfer", Elapsed: transfer},
+	}
+}
+
+// ParseStatusCodes parses a list of status codes and ranges like `200-299,301`.
+// `2xx` is shorthand for `200-299`.
+func ParseStatusCodes(spec string) (StatusCodes, error) {
+	var codes StatusCodes
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if len(part) == 0 {
+			continue
+		}
+
+		var low, high string
+		if len(part) == 3 && strings.HasSuffix(strings.ToLower(part), "xx") {
+			low, high = part[:1]+"00", part[:1]+"99"
+		} else if pieces := strings.SplitN(part, "-", 2); len(pieces) == 2 {
+			low, high = strings.TrimSpace(pieces[0]), strings.TrimSpace(pieces[1])
+		} else {
+			low, high = part, part
+		}
+
+		lowCode, lowErr := strconv.Atoi(low)
+		highCode, highErr := strconv.Atoi(high)
+		if lowErr != nil || highErr != nil || lowCode > highCode {
+			return nil, fmt.Errorf("invalid status code range: %q", part)
+		}
+		codes = append(codes, StatusCodeRange{Low: lowCode, High: highCode})
+	}
+	if len(codes) == 0 {
+		return nil, fmt.Errorf("no status codes in %q", spec)
+	}
+	return codes, nil
+}
+
+// StatusCodeRange is an inclusive range of http status codes.
+type StatusCodeRange struct {
+	Low  int
+	High int
+}
+
+// String returns the range as it would be configured.
+func (scr StatusCodeRange) String() string {
+	if scr.Low == scr.High {
+		return strconv.Itoa(scr.Low)
+	}
+	return fmt.Sprintf("%d-%d", scr.Low, scr.High)
+}
+
+// StatusCodes is a set of acceptable http status codes.
+type StatusCodes []StatusCodeRange
+
+// Contains returns if a status code is in any of the ranges.
+func (sc StatusCodes) Contains(statusCode int) bool {
+	for _, scr := range sc {
+		if statusCode >= scr.Low && statusCode <= scr.High {
+			return true
+		}
+	}
+	return false
+}
+
+// String returns the codes as they would be configured.
+func (sc StatusCodes) String() string {
+	var ranges []string
+	for _, scr := range sc {
+		ranges = append(ranges, scr.String())
+	}
+	return strings.Join(ranges, ",")
+}
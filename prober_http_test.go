@@ -0,0 +1,312 @@
+package health
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/blendlabs/go-assert"
+)
+
+func TestParseStatusCodes(t *testing.T) {
+	assert := assert.New(t)
+
+	codes, err := ParseStatusCodes("200-299, 301,4xx")
+	assert.Nil(err)
+	assert.Equal("200-299,301,400-499", codes.String())
+	assert.True(codes.Contains(204))
+	assert.True(codes.Contains(301))
+	assert.True(codes.Contains(404))
+	assert.False(codes.Contains(302))
+	assert.False(codes.Contains(500))
+
+	_, err = ParseStatusCodes("299-200")
+	assert.NotNil(err)
+	_, err = ParseStatusCodes("ok")
+	assert.NotNil(err)
+	_, err = ParseStatusCodes("")
+	assert.NotNil(err)
+}
+
+func TestHTTPProberRedirects(t *testing.T) {
+	assert := assert.New(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			http.Redirect(rw, r, "/login", http.StatusFound)
+		case "/loop":
+			http.Redirect(rw, r, "/loop", http.StatusFound)
+		case "/empty":
+			rw.WriteHeader(http.StatusNoContent)
+		default:
+			rw.Write([]byte("login"))
+		}
+	}))
+	defer server.Close()
+
+	probe := func(config HostConfig) ProbeResult {
+		hostURL, err := url.Parse(config.URL)
+		assert.Nil(err)
+		prober, err := NewProber(hostURL, &config)
+		assert.Nil(err)
+		return prober.Probe(time.Second)
+	}
+
+	result := probe(HostConfig{URL: server.URL})
+	assert.Nil(result.Err)
+
+	result = probe(HostConfig{URL: server.URL, ExpectFinalURL: server.URL + "/login"})
+	assert.Nil(result.Err)
+
+	result = probe(HostConfig{URL: server.URL, ExpectFinalURL: server.URL + "/home"})
+	assert.NotNil(result.Err)
+
+	noFollow := false
+	result = probe(HostConfig{URL: server.URL, FollowRedirects: &noFollow})
+	assert.NotNil(result.Err)
+
+	result = probe(HostConfig{URL: server.URL, FollowRedirects: &noFollow, ExpectStatus: "302"})
+	assert.Nil(result.Err)
+
+	result = probe(HostConfig{URL: server.URL + "/loop", MaxRedirects: 3})
+	assert.NotNil(result.Err)
+
+	result = probe(HostConfig{URL: server.URL + "/empty"})
+	assert.NotNil(result.Err)
+
+	result = probe(HostConfig{URL: server.URL + "/empty", ExpectStatus: "2xx"})
+	assert.Nil(result.Err)
+}
+
+func TestHTTPProberRequestOptions(t *testing.T) {
+	assert := assert.New(t)
+
+	var method, token, custom, body string
+	var username, password string
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		method = r.Method
+		token = r.Header.Get("Authorization")
+		custom = r.Header.Get("X-Check")
+		username, password, _ = r.BasicAuth()
+		contents, _ := ioutil.ReadAll(r.Body)
+		body = string(contents)
+	}))
+	defer server.Close()
+
+	bodyFile, err := ioutil.TempFile("", "health-body")
+	assert.Nil(err)
+	defer os.Remove(bodyFile.Name())
+	bodyFile.WriteString(`{"ping":true}`)
+	bodyFile.Close()
+
+	hostURL, err := url.Parse(server.URL)
+	assert.Nil(err)
+	prober, err := NewProber(hostURL, &HostConfig{
+		URL:         server.URL,
+		Method:      "post",
+		Headers:     map[string]string{"X-Check": "health"},
+		BodyFile:    bodyFile.Name(),
+		BearerToken: "abc123",
+	})
+	assert.Nil(err)
+	result := prober.Probe(time.Second)
+	assert.Nil(result.Err)
+	assert.Equal("POST", method)
+	assert.Equal("Bearer abc123", token)
+	assert.Equal("health", custom)
+	assert.Equal(`{"ping":true}`, body)
+
+	prober, err = NewProber(hostURL, &HostConfig{
+		URL:       server.URL,
+		Method:    "PUT",
+		Body:      "payload",
+		BasicAuth: &BasicAuth{Username: "health", Password: "secret"},
+	})
+	assert.Nil(err)
+	result = prober.Probe(time.Second)
+	assert.Nil(result.Err)
+	assert.Equal("PUT", method)
+	assert.Equal("health", username)
+	assert.Equal("secret", password)
+	assert.Equal("payload", body)
+
+	_, err = NewProber(hostURL, &HostConfig{URL: server.URL, BodyFile: "/does/not/exist"})
+	assert.NotNil(err)
+}
+
+func TestHTTPProberTimings(t *testing.T) {
+	assert := assert.New(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		time.Sleep(10 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	hostURL, err := url.Parse(server.URL)
+	assert.Nil(err)
+	prober, err := NewProber(hostURL, &HostConfig{URL: server.URL})
+	assert.Nil(err)
+
+	result := prober.Probe(time.Second)
+	assert.Nil(result.Err)
+	assert.Len(result.Timings, 5)
+
+	var names []string
+	for _, timing := range result.Timings {
+		names = append(names, timing.Name)
+	}
+	assert.Equal([]string{"dns", "connect", "tls", "ttfb", "transfer"}, names)
+	assert.True(result.Timings[3].Elapsed >= 10*time.Millisecond)
+	assert.True(result.Timings[3].Elapsed <= result.Elapsed)
+}
+
+func TestHTTPProberHealthJSON(t *testing.T) {
+	assert := assert.New(t)
+
+	var status, document string
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		rw.Header().Set("Content-Type", ContentTypeHealthJSON)
+		if status == "fail" {
+			rw.WriteHeader(http.StatusServiceUnavailable)
+		}
+		rw.Write([]byte(document))
+	}))
+	defer server.Close()
+
+	hostURL, err := url.Parse(server.URL)
+	assert.Nil(err)
+	prober, err := NewProber(hostURL, &HostConfig{URL: server.URL})
+	assert.Nil(err)
+
+	status, document = "pass", `{"status":"pass","checks":{"db":[{"status":"pass"}]}}`
+	result := prober.Probe(time.Second)
+	assert.Nil(result.Err)
+	assert.Equal(StatusUp, result.Status)
+	assert.Equal("checks: 1 up", result.Details)
+
+	status, document = "warn", `{"status":"warn","checks":{"db":[{"status":"warn"}]}}`
+	result = prober.Probe(time.Second)
+	assert.Nil(result.Err)
+	assert.Equal(StatusWarn, result.Status)
+
+	status, document = "fail", `{"status":"fail","checks":{"db":[{"status":"fail","output":"timeout"}]}}`
+	result = prober.Probe(time.Second)
+	assert.Equal(StatusDown, result.Status)
+	assert.NotNil(result.Err)
+	assert.Equal("health fail, failing checks: db (timeout)", result.Err.Error())
+}
+
+func TestHTTPProberMetricAssertions(t *testing.T) {
+	assert := assert.New(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		rw.Write([]byte("queue_depth{queue=\"jobs\"} 12\nup 1\n"))
+	}))
+	defer server.Close()
+
+	hostURL, err := url.Parse(server.URL)
+	assert.Nil(err)
+	prober, err := NewProber(hostURL, &HostConfig{URL: server.URL, MetricAssertions: []string{`queue_depth{queue="jobs"} < 1000`, `up == 1`}})
+	assert.Nil(err)
+	result := prober.Probe(time.Second)
+	assert.Nil(result.Err)
+	assert.Len(result.Metrics, 2)
+
+	prober, err = NewProber(hostURL, &HostConfig{URL: server.URL, MetricAssertions: []string{`queue_depth{queue="jobs"} < 10`}})
+	assert.Nil(err)
+	result = prober.Probe(time.Second)
+	assert.Equal(StatusDown, result.Status)
+	assert.Len(result.Metrics, 1)
+
+	_, err = NewProber(hostURL, &HostConfig{URL: server.URL, MetricAssertions: []string{`queue_depth ~ 10`}})
+	assert.NotNil(err)
+}
+
+func TestHTTPProberFreshness(t *testing.T) {
+	assert := assert.New(t)
+
+	var completedAt time.Time
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		rw.Header().Set("Last-Modified", completedAt.UTC().Format(http.TimeFormat))
+		rw.Write([]byte(`{"last_job_completed_at":"` + completedAt.Format(time.RFC3339) + `"}`))
+	}))
+	defer server.Close()
+
+	hostURL, err := url.Parse(server.URL)
+	assert.Nil(err)
+	prober, err := NewProber(hostURL, &HostConfig{URL: server.URL, Freshness: &FreshnessConfig{JSONPath: "last_job_completed_at", WarnAge: time.Hour, MaxAge: 3 * time.Hour}})
+	assert.Nil(err)
+
+	completedAt = time.Now().Add(-10 * time.Minute)
+	result := prober.Probe(time.Second)
+	assert.Nil(result.Err)
+	assert.Equal(StatusUp, result.Status)
+	assert.Equal("last_job_completed_at: 10m ago", result.Details)
+
+	completedAt = time.Now().Add(-2 * time.Hour)
+	result = prober.Probe(time.Second)
+	assert.Nil(result.Err)
+	assert.Equal(StatusWarn, result.Status)
+
+	completedAt = time.Now().Add(-4 * time.Hour)
+	result = prober.Probe(time.Second)
+	assert.Equal(StatusDown, result.Status)
+	assert.Equal("last_job_completed_at is 4h old, expected under 3h", result.Err.Error())
+
+	prober, err = NewProber(hostURL, &HostConfig{URL: server.URL, Freshness: &FreshnessConfig{Header: "Last-Modified", MaxAge: 5 * time.Hour}})
+	assert.Nil(err)
+	result = prober.Probe(time.Second)
+	assert.Nil(result.Err)
+	assert.Equal("Last-Modified: 4h ago", result.Details)
+
+	_, err = NewProber(hostURL, &HostConfig{URL: server.URL, Freshness: &FreshnessConfig{MaxAge: time.Hour}})
+	assert.NotNil(err)
+}
+
+func TestHTTPProberFreshnessWithHealthJSONWarning(t *testing.T) {
+	assert := assert.New(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		rw.Header().Set("Content-Type", "application/health+json")
+		rw.Header().Set("Last-Modified", time.Now().Add(-2*time.Hour).UTC().Format(http.TimeFormat))
+		rw.Write([]byte(`{"status":"warn","checks":{"db":[{"status":"fail","output":"replica lag"}]}}`))
+	}))
+	defer server.Close()
+
+	hostURL, err := url.Parse(server.URL)
+	assert.Nil(err)
+	prober, err := NewProber(hostURL, &HostConfig{URL: server.URL, Freshness: &FreshnessConfig{Header: "Last-Modified", WarnAge: time.Hour}})
+	assert.Nil(err)
+
+	result := prober.Probe(time.Second)
+	assert.Equal(StatusWarn, result.Status)
+	assert.NotNil(result.Err)
+	assert.Equal("checks: 1 down, Last-Modified: 2h ago", result.Details)
+}
+
+func TestHTTPProberBodyAssertions(t *testing.T) {
+	assert := assert.New(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		rw.Write([]byte(`{"status":"degraded"}`))
+	}))
+	defer server.Close()
+
+	hostURL, err := url.Parse(server.URL)
+	assert.Nil(err)
+	prober, err := NewProber(hostURL, &HostConfig{
+		URL:        server.URL,
+		Assertions: []BodyAssertion{{JSONPath: "status", Equals: "ok"}},
+	})
+	assert.Nil(err)
+
+	result := prober.Probe(time.Second)
+	assert.Equal(StatusDown, result.Status)
+	assert.NotNil(result.Err)
+	assert.Contains("degraded", result.Err.Error())
+}
@@ -0,0 +1,205 @@
+package health
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultLogWindow is the default sliding window for log pattern matches.
+	DefaultLogWindow = 5 * time.Minute
+
+	// logLastLines is the number of matching lines shown in a log host's error.
+	logLastLines = 3
+	// maxLogLineLength is the longest line kept from a log, and the longest partial line buffered.
+	maxLogLineLength = 16 * 1024
+)
+
+// LogWatchConfig is the pattern and thresholds for a `log://` host.
+type LogWatchConfig struct {
+	Pattern   string        `json:"pattern" yaml:"pattern"`
+	Window    time.Duration `json:"window" yaml:"window"`
+	WarnAbove *int          `json:"warn_above" yaml:"warnAbove"`
+	DownAbove *int          `json:"down_above" yaml:"downAbove"`
+}
+
+// NewLogProber returns a new prober that watches a log file for urls of the
+// form `log:///var/log/app.log`, configured by the host's `logWatch` block.
+func NewLogProber(hostURL *url.URL, config *HostConfig) (Prober, error) {
+	if len(hostURL.Path) == 0 {
+		return nil, fmt.Errorf("log host must include a path: %s", hostURL.String())
+	}
+	watch := config.LogWatch
+	if watch == nil || len(watch.Pattern) == 0 {
+		return nil, fmt.Errorf("log host must set `logWatch.pattern`: %s", hostURL.String())
+	}
+	if watch.WarnAbove == nil && watch.DownAbove == nil {
+		return nil, fmt.Errorf("log host must set `logWatch.warnAbove` or `logWatch.downAbove`: %s", hostURL.String())
+	}
+	pattern, err := regexp.Compile(watch.Pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid log `pattern` %q: %v", watch.Pattern, err)
+	}
+
+	prober := &LogProber{
+		path:      hostURL.Path,
+		pattern:   pattern,
+		window:    watch.Window,
+		warnAbove: -1,
+		downAbove: -1,
+	}
+	if prober.window <= 0 {
+		prober.window = DefaultLogWindow
+	}
+	if watch.WarnAbove != nil {
+		prober.warnAbove = *watch.WarnAbove
+	}
+	if watch.DownAbove != nil {
+		prober.downAbove = *watch.DownAbove
+	}
+	return prober, nil
+}
+
+// LogProber checks a host by tailing its log file and counting lines that
+// match a pattern within a sliding window. It starts at the end of the file,
+// and starts over from the beginning when the file is rotated or truncated.
+type LogProber struct {
+	sync.Mutex
+	path      string
+	pattern   *regexp.Regexp
+	window    time.Duration
+	warnAbove int
+	downAbove int
+
+	file      *os.File
+	info      os.FileInfo
+	offset    int64
+	partial   []byte
+	matches   []time.Time
+	lastLines []string
+}
+
+// Probe reads any lines added since the last probe and checks the number of
+// matches in the window against the thresholds.
+func (lp *LogProber) Probe(timeout time.Duration) ProbeResult {
+	lp.Lock()
+	defer lp.Unlock()
+
+	begin := time.Now()
+	if err := lp.read(begin); err != nil {
+		return NewProbeResult(time.Now().Sub(begin), err)
+	}
+	elapsed := time.Now().Sub(begin)
+
+	cutoff := begin.Add(-lp.window)
+	for len(lp.matches) > 0 && lp.matches[0].Before(cutoff) {
+		lp.matches = lp.matches[1:]
+	}
+
+	count := len(lp.matches)
+	result := NewProbeResult(elapsed, nil)
+	result.Metrics = []Metric{{Name: "matches", Value: float64(count)}}
+	switch {
+	case lp.downAbove >= 0 && count > lp.downAbove:
+		result.Status = StatusDown
+	case lp.warnAbove >= 0 && count > lp.warnAbove:
+		result.Status = StatusWarn
+	default:
+		return result
+	}
+
+	last := lp.lastLines
+	if len(last) > count {
+		last = last[len(last)-count:]
+	}
+	result.Err = fmt.Errorf("%d matches of %q in %s: %s", count, lp.pattern.String(), FormatDuration(lp.window), strings.Join(last, " | "))
+	return result
+}
+
+// read reads the lines appended to the log since the last read, reopening it if it was rotated or truncated.
+func (lp *LogProber) read(now time.Time) error {
+	info, err := os.Stat(lp.path)
+	if err != nil {
+		if lp.file != nil {
+			// rotated away; finish the old file, and read the new one from the start when it appears.
+			lp.readLines(now)
+			lp.close()
+			lp.offset = 0
+		}
+		return err
+	}
+
+	if lp.file != nil && !os.SameFile(lp.info, info) {
+		// rotated; finish the old file before moving to the new one.
+		lp.readLines(now)
+		lp.close()
+		lp.offset = 0
+	}
+	if lp.file == nil {
+		file, err := os.Open(lp.path)
+		if err != nil {
+			return err
+		}
+		if lp.info == nil {
+			// first open, so only watch for new lines.
+			lp.offset = info.Size()
+		}
+		lp.file = file
+	}
+	lp.info = info
+	if info.Size() < lp.offset {
+		lp.offset = 0
+		lp.partial = nil
+	}
+	return lp.readLines(now)
+}
+
+// readLines reads from the current offset to the end of the open file.
+func (lp *LogProber) readLines(now time.Time) error {
+	if _, err := lp.file.Seek(lp.offset, io.SeekStart); err != nil {
+		return err
+	}
+	data, err := ioutil.ReadAll(lp.file)
+	lp.offset += int64(len(data))
+	if err != nil {
+		return err
+	}
+
+	data = append(lp.partial, data...)
+	lines := bytes.Split(data, []byte("\n"))
+	lp.partial = lines[len(lines)-1]
+	if len(lp.partial) > maxLogLineLength {
+		lp.partial = lp.partial[:maxLogLineLength]
+	}
+	lp.partial = append([]byte(nil), lp.partial...)
+
+	for _, line := range lines[:len(lines)-1] {
+		if len(line) > maxLogLineLength {
+			line = line[:maxLogLineLength]
+		}
+		if lp.pattern.Match(line) {
+			lp.matches = append(lp.matches, now)
+			lp.lastLines = append(lp.lastLines, strings.TrimSpace(string(line)))
+			if len(lp.lastLines) > logLastLines {
+				lp.lastLines = lp.lastLines[1:]
+			}
+		}
+	}
+	return nil
+}
+
+func (lp *LogProber) close() {
+	if lp.file != nil {
+		lp.file.Close()
+		lp.file = nil
+	}
+	lp.partial = nil
+}
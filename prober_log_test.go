@@ -0,0 +1,115 @@
+package health
+
+import (
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/blendlabs/go-assert"
+)
+
+func newLogProber(assert *assert.Assertions, path string, watch *LogWatchConfig) *LogProber {
+	hostURL, err := url.Parse("log://" + path)
+	assert.Nil(err)
+	prober, err := NewProber(hostURL, &HostConfig{URL: hostURL.String(), LogWatch: watch})
+	assert.Nil(err)
+	return prober.(*LogProber)
+}
+
+func appendLog(assert *assert.Assertions, path, lines string) {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	assert.Nil(err)
+	defer file.Close()
+	_, err = file.WriteString(lines)
+	assert.Nil(err)
+}
+
+func TestLogProber(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "health-log")
+	assert.Nil(err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "app.log")
+	appendLog(assert, path, "ERROR before we started watching\n")
+
+	warnAbove, downAbove := 1, 3
+	prober := newLogProber(assert, path, &LogWatchConfig{Pattern: "ERROR", WarnAbove: &warnAbove, DownAbove: &downAbove})
+
+	result := prober.Probe(time.Second)
+	assert.Equal(StatusUp, result.Status)
+	assert.Equal("matches=0", result.Metrics[0].String())
+
+	appendLog(assert, path, "INFO ok\nERROR one\nERROR tw")
+	result = prober.Probe(time.Second)
+	assert.Equal(StatusUp, result.Status)
+	assert.Equal(1.0, result.Metrics[0].Value)
+
+	appendLog(assert, path, "o\n")
+	result = prober.Probe(time.Second)
+	assert.Equal(StatusWarn, result.Status)
+	assert.Equal(`2 matches of "ERROR" in 5m: ERROR one | ERROR two`, result.Err.Error())
+
+	appendLog(assert, path, "ERROR three\nERROR four\n")
+	result = prober.Probe(time.Second)
+	assert.Equal(StatusDown, result.Status)
+	assert.Equal(`4 matches of "ERROR" in 5m: ERROR two | ERROR three | ERROR four`, result.Err.Error())
+
+	// matches age out of the window.
+	prober.window = time.Nanosecond
+	result = prober.Probe(time.Second)
+	assert.Equal(StatusUp, result.Status)
+	assert.Nil(result.Err)
+}
+
+func TestLogProberRotation(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "health-log")
+	assert.Nil(err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "app.log")
+	appendLog(assert, path, "INFO starting\n")
+
+	downAbove := 0
+	prober := newLogProber(assert, path, &LogWatchConfig{Pattern: "panic:", DownAbove: &downAbove})
+	assert.Equal(StatusUp, prober.Probe(time.Second).Status)
+
+	// rotated: the rest of the old file and all of the new one are read.
+	appendLog(assert, path, "panic: old file\n")
+	assert.Nil(os.Rename(path, path+".1"))
+	appendLog(assert, path, "panic: new file\n")
+	result := prober.Probe(time.Second)
+	assert.Equal(StatusDown, result.Status)
+	assert.Equal(2.0, result.Metrics[0].Value)
+
+	// truncated: the file is read again from the start, if it is now shorter than what was read.
+	prober.window = time.Nanosecond
+	prober.Probe(time.Second)
+	prober.window = time.Minute
+	assert.Nil(ioutil.WriteFile(path, []byte("panic: trunc\n"), 0644))
+	result = prober.Probe(time.Second)
+	assert.Equal(1.0, result.Metrics[0].Value)
+	assert.Contains("panic: trunc", result.Err.Error())
+
+	assert.Nil(os.Remove(path))
+	assert.Equal(StatusDown, prober.Probe(time.Second).Status)
+}
+
+func TestLogProberValidation(t *testing.T) {
+	assert := assert.New(t)
+
+	hostURL, err := url.Parse("log:///var/log/app.log")
+	assert.Nil(err)
+	downAbove := 10
+
+	_, err = NewProber(hostURL, &HostConfig{URL: hostURL.String()})
+	assert.NotNil(err)
+	_, err = NewProber(hostURL, &HostConfig{URL: hostURL.String(), LogWatch: &LogWatchConfig{Pattern: "ERROR"}})
+	assert.NotNil(err)
+	_, err = NewProber(hostURL, &HostConfig{URL: hostURL.String(), LogWatch: &LogWatchConfig{Pattern: "(", DownAbove: &downAbove}})
+	assert.NotNil(err)
+}
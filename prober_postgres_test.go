@@ -0,0 +1,217 @@
+package health
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/tls"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/blendlabs/go-assert"
+)
+
+// servePostgres is a minimal stand-in for a postgres server. It asks for a
+// cleartext password if `password` is set, and answers every query with `SELECT 1`.
+// With a `tlsConfig` it only accepts ssl connections, like a `hostssl` only
+// `pg_hba.conf`; without one it declines ssl.
+func servePostgres(t *testing.T, password string, tlsConfig *tls.Config) net.Listener {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writeMessage := func(conn net.Conn, messageType byte, payload []byte) {
+		header := []byte{messageType, 0, 0, 0, 0}
+		binary.BigEndian.PutUint32(header[1:], uint32(len(payload)+4))
+		conn.Write(append(header, payload...))
+	}
+	ready := func(conn net.Conn) {
+		writeMessage(conn, 'S', []byte("server_version\x0016.2\x00"))
+		writeMessage(conn, 'Z', []byte{'I'})
+	}
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				defer conn.Close()
+				reader := bufio.NewReader(conn)
+
+				header := make([]byte, 4)
+				if _, err := io.ReadFull(reader, header); err != nil {
+					return
+				}
+				startup := make([]byte, binary.BigEndian.Uint32(header)-4)
+				io.ReadFull(reader, startup)
+
+				if binary.BigEndian.Uint32(startup) == postgresSSLRequestCode {
+					if tlsConfig == nil {
+						conn.Write([]byte{'N'})
+					} else {
+						conn.Write([]byte{'S'})
+						conn = tls.Server(conn, tlsConfig)
+						defer conn.Close()
+						reader = bufio.NewReader(conn)
+					}
+					if _, err := io.ReadFull(reader, header); err != nil {
+						return
+					}
+					startup = make([]byte, binary.BigEndian.Uint32(header)-4)
+					io.ReadFull(reader, startup)
+				} else if tlsConfig != nil {
+					writeMessage(conn, 'E', []byte("SFATAL\x00C28000\x00Mno pg_hba.conf entry for host, SSL off\x00\x00"))
+					return
+				}
+
+				if len(password) > 0 {
+					writeMessage(conn, 'R', []byte{0, 0, 0, postgresAuthCleartextPassword})
+				} else {
+					writeMessage(conn, 'R', []byte{0, 0, 0, postgresAuthOK})
+					ready(conn)
+				}
+
+				for {
+					header := make([]byte, 5)
+					if _, err := io.ReadFull(reader, header); err != nil {
+						return
+					}
+					payload := make([]byte, binary.BigEndian.Uint32(header[1:])-4)
+					io.ReadFull(reader, payload)
+
+					switch header[0] {
+					case 'p':
+						if string(bytes.TrimRight(payload, "\x00")) != password {
+							writeMessage(conn, 'E', []byte("SFATAL\x00C28P01\x00Mpassword authentication failed\x00\x00"))
+							return
+						}
+						writeMessage(conn, 'R', []byte{0, 0, 0, postgresAuthOK})
+						ready(conn)
+					case 'Q':
+						writeMessage(conn, 'C', []byte("SELECT 1\x00"))
+						writeMessage(conn, 'Z', []byte{'I'})
+					case 'X':
+						return
+					}
+				}
+			}(conn)
+		}
+	}()
+	return listener
+}
+
+func TestPostgresProberHandshake(t *testing.T) {
+	assert := assert.New(t)
+
+	server := servePostgres(t, "secret", nil)
+	defer server.Close()
+
+	result := probeHost(assert, HostConfig{URL: "postgres://" + server.Addr().String()})
+	assert.Nil(result.Err)
+	assert.Equal(StatusUp, result.Status)
+	assert.Len(result.Timings, 2)
+}
+
+func TestPostgresProberQuery(t *testing.T) {
+	assert := assert.New(t)
+
+	server := servePostgres(t, "secret", nil)
+	defer server.Close()
+
+	result := probeHost(assert, HostConfig{URL: "postgres://health:secret@" + server.Addr().String() + "/app?query=true"})
+	assert.Nil(result.Err)
+	assert.Equal(StatusUp, result.Status)
+	assert.Equal("server 16.2", result.Details)
+	assert.Len(result.Timings, 3)
+	assert.Equal("query", result.Timings[2].Name)
+
+	result = probeHost(assert, HostConfig{URL: "postgres://health:wrong@" + server.Addr().String() + "/app?query=true"})
+	assert.NotNil(result.Err)
+	assert.Equal(StatusDown, result.Status)
+	assert.Contains("password authentication failed", result.Err.Error())
+}
+
+func TestPostgresProberQueryWithoutPassword(t *testing.T) {
+	assert := assert.New(t)
+
+	trusted := servePostgres(t, "", nil)
+	defer trusted.Close()
+	result := probeHost(assert, HostConfig{URL: "postgres://health@" + trusted.Addr().String() + "/app?query=true"})
+	assert.Nil(result.Err)
+	assert.Equal(StatusUp, result.Status)
+	assert.Len(result.Timings, 3)
+
+	server := servePostgres(t, "secret", nil)
+	defer server.Close()
+	result = probeHost(assert, HostConfig{URL: "postgres://health@" + server.Addr().String() + "/app?query=true"})
+	assert.NotNil(result.Err)
+	assert.Equal(StatusDown, result.Status)
+	assert.Contains("none was given", result.Err.Error())
+}
+
+func TestPostgresProberSSLMode(t *testing.T) {
+	assert := assert.New(t)
+
+	certServer := httptest.NewTLSServer(http.NotFoundHandler())
+	tlsConfig := certServer.TLS
+	certServer.Close()
+
+	sslServer := servePostgres(t, "secret", tlsConfig)
+	defer sslServer.Close()
+	plainServer := servePostgres(t, "secret", nil)
+	defer plainServer.Close()
+
+	for _, sslMode := range []string{"", "prefer", "require"} {
+		result := probeHost(assert, HostConfig{URL: "postgres://health:secret@" + sslServer.Addr().String() + "/app?query=true&sslmode=" + sslMode})
+		assert.Nil(result.Err, sslMode)
+		assert.Equal("tls", result.Timings[1].Name)
+	}
+	result := probeHost(assert, HostConfig{URL: "postgres://health:secret@" + sslServer.Addr().String() + "/app?sslmode=disable"})
+	assert.NotNil(result.Err)
+	assert.Contains("SSL off", result.Err.Error())
+	result = probeHost(assert, HostConfig{URL: "postgres://health:secret@" + sslServer.Addr().String() + "/app?sslmode=verify-full"})
+	assert.NotNil(result.Err, "the test certificate is not trusted")
+
+	result = probeHost(assert, HostConfig{URL: "postgres://health:secret@" + plainServer.Addr().String() + "/app?query=true"})
+	assert.Nil(result.Err)
+	assert.Len(result.Timings, 3)
+	result = probeHost(assert, HostConfig{URL: "postgres://health:secret@" + plainServer.Addr().String() + "/app?sslmode=require"})
+	assert.NotNil(result.Err)
+	assert.Contains("does not support ssl", result.Err.Error())
+
+	hostURL, err := url.Parse("postgres://localhost/app?sslmode=allow")
+	assert.Nil(err)
+	_, err = NewProber(hostURL, &HostConfig{URL: hostURL.String()})
+	assert.NotNil(err)
+}
+
+func TestPostgresMD5Password(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal("md5bb41a296aab6baccb36ff243a562abff", postgresMD5Password("postgres", "secret", []byte{1, 2, 3, 4}))
+}
+
+func TestSCRAMSHA256(t *testing.T) {
+	assert := assert.New(t)
+
+	// the exchange from RFC 7677, section 3, with the username left out of the
+	// bare message as postgres does (which changes the proof and signature).
+	scram := &scramSHA256{password: "pencil", clientNonce: "rOprNGfwEbeRWgbNEkqO"}
+	assert.Equal("n,,n=,r=rOprNGfwEbeRWgbNEkqO", scram.ClientFirstMessage())
+
+	final, err := scram.ClientFinalMessage("r=rOprNGfwEbeRWgbNEkqO%hvYDpWUa2RaTCAfuxFIlj)hNlF$k0,s=W22ZaJ0SNY7soEsUEjb6gQ==,i=4096")
+	assert.Nil(err)
+	assert.Equal("c=biws,r=rOprNGfwEbeRWgbNEkqO%hvYDpWUa2RaTCAfuxFIlj)hNlF$k0,p=qvT2SWdEH5Q06albL+hjSYuUhCG7VndFyzIb7CK4n9k=", final)
+	assert.Nil(scram.VerifyServerFinalMessage("v=3HO6Qt1M4MKJrmlKaoOqLAI0/0TV0HZe7J9H3MBtSOg="))
+	assert.NotNil(scram.VerifyServerFinalMessage("v=bm90IHRoZSBzaWduYXR1cmU="))
+	assert.NotNil(scram.VerifyServerFinalMessage("e=invalid-proof"))
+
+	_, err = scram.ClientFinalMessage("r=someoneelse,s=W22ZaJ0SNY7soEsUEjb6gQ==,i=4096")
+	assert.NotNil(err)
+}
@@ -0,0 +1,236 @@
+package health
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultRedisPort is the port used for redis hosts that don't specify one.
+	DefaultRedisPort = "6379"
+)
+
+// NewRedisProber returns a new redis prober for urls of the form
+// `redis://[[user]:password@]host[:port][?role=master&maxLag=10]`, or
+// `rediss://` for servers that use tls.
+func NewRedisProber(hostURL *url.URL, config *HostConfig) (Prober, error) {
+	if len(hostURL.Hostname()) == 0 {
+		return nil, fmt.Errorf("redis host must include a hostname: %s", hostURL.String())
+	}
+	port := hostURL.Port()
+	if len(port) == 0 {
+		port = DefaultRedisPort
+	}
+
+	prober := &RedisProber{
+		addr:       config.dialAddr(net.JoinHostPort(hostURL.Hostname(), port)),
+		serverName: hostURL.Hostname(),
+		useTLS:     strings.EqualFold(hostURL.Scheme, "rediss"),
+		maxLag:     -1,
+	}
+	if hostURL.User != nil {
+		prober.username = hostURL.User.Username()
+		prober.password, _ = hostURL.User.Password()
+	}
+
+	query := hostURL.Query()
+	switch role := strings.ToLower(query.Get("role")); role {
+	case "":
+	case "master", "primary":
+		prober.role = "master"
+	case "replica", "slave":
+		prober.role = "slave"
+	default:
+		return nil, fmt.Errorf("invalid redis `role`: %q", role)
+	}
+	if maxLag := query.Get("maxLag"); len(maxLag) > 0 {
+		value, err := strconv.Atoi(maxLag)
+		if err != nil || value < 0 {
+			return nil, fmt.Errorf("invalid redis `maxLag`: %q", maxLag)
+		}
+		prober.maxLag = value
+	}
+	return prober, nil
+}
+
+// RedisProber checks a host by sending `PING`, and optionally asserts the
+// server's replication role and lag from `INFO replication`.
+type RedisProber struct {
+	addr       string
+	serverName string
+	useTLS     bool
+	username   string
+	password   string
+	role       string
+	maxLag     int
+}
+
+// Probe pings the server and checks replication.
+func (rp *RedisProber) Probe(timeout time.Duration) ProbeResult {
+	dialer := &net.Dialer{Timeout: timeout}
+
+	begin := time.Now()
+	var conn net.Conn
+	var err error
+	if rp.useTLS {
+		conn, err = tls.DialWithDialer(dialer, "tcp", rp.addr, &tls.Config{ServerName: rp.serverName})
+	} else {
+		conn, err = dialer.Dial("tcp", rp.addr)
+	}
+	connected := time.Now()
+	if err != nil {
+		return NewProbeResult(connected.Sub(begin), err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(begin.Add(timeout))
+
+	rc := &redisConn{conn: conn, reader: bufio.NewReader(conn)}
+	timings := []Timing{{Name: "connect", Elapsed: connected.Sub(begin)}}
+
+	if len(rp.password) > 0 {
+		args := []string{"AUTH", rp.password}
+		if len(rp.username) > 0 {
+			args = []string{"AUTH", rp.username, rp.password}
+		}
+		if _, err := rc.do(args...); err != nil {
+			return NewProbeResult(time.Now().Sub(begin), err)
+		}
+	}
+
+	pingStart := time.Now()
+	reply, err := rc.do("PING")
+	timings = append(timings, Timing{Name: "ping", Elapsed: time.Now().Sub(pingStart)})
+	if err == nil && reply != "PONG" {
+		err = fmt.Errorf("redis: unexpected reply to PING: %q", reply)
+	}
+	if err != nil || (len(rp.role) == 0 && rp.maxLag < 0) {
+		result := NewProbeResult(time.Now().Sub(begin), err)
+		result.Timings = timings
+		return result
+	}
+
+	info, err := rc.do("INFO", "replication")
+	var details string
+	if err == nil {
+		details, err = rp.checkReplication(info)
+	}
+	result := NewProbeResult(time.Now().Sub(begin), err)
+	result.Timings = timings
+	result.Details = details
+	return result
+}
+
+// checkReplication asserts the role and lag reported by `INFO replication`.
+func (rp *RedisProber) checkReplication(info string) (string, error) {
+	fields := map[string]string{}
+	var replicaLags []int
+	for _, line := range strings.Split(info, "\n") {
+		line = strings.TrimSpace(line)
+		pieces := strings.SplitN(line, ":", 2)
+		if len(pieces) != 2 {
+			continue
+		}
+		fields[pieces[0]] = pieces[1]
+
+		// slaveN:ip=10.0.0.2,port=6379,state=online,offset=1234,lag=0
+		if strings.HasPrefix(pieces[0], "slave") && strings.Contains(pieces[1], "lag=") {
+			for _, attribute := range strings.Split(pieces[1], ",") {
+				if strings.HasPrefix(attribute, "lag=") {
+					if lag, err := strconv.Atoi(strings.TrimPrefix(attribute, "lag=")); err == nil {
+						replicaLags = append(replicaLags, lag)
+					}
+				}
+			}
+		}
+	}
+
+	role := fields["role"]
+	if len(role) == 0 {
+		return "", fmt.Errorf("redis: no role in INFO replication")
+	}
+	details := fmt.Sprintf("role: %s", role)
+	if len(rp.role) > 0 && role != rp.role {
+		return details, fmt.Errorf("redis: expected role %s, got %s", rp.role, role)
+	}
+	if rp.maxLag < 0 {
+		return details, nil
+	}
+
+	lag := 0
+	if role == "slave" {
+		if status := fields["master_link_status"]; status != "up" {
+			return details, fmt.Errorf("redis: replication link is %s", status)
+		}
+		lag, _ = strconv.Atoi(fields["master_last_io_seconds_ago"])
+	} else {
+		for _, replicaLag := range replicaLags {
+			if replicaLag > lag {
+				lag = replicaLag
+			}
+		}
+	}
+	details = fmt.Sprintf("%s, lag: %ds", details, lag)
+	if lag > rp.maxLag {
+		return details, fmt.Errorf("redis: replication lag %ds exceeds %ds", lag, rp.maxLag)
+	}
+	return details, nil
+}
+
+// redisConn sends commands and reads replies in the redis serialization protocol.
+type redisConn struct {
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+// do sends a command and returns its reply as a string.
+func (rc *redisConn) do(args ...string) (string, error) {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&buf, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if _, err := rc.conn.Write(buf.Bytes()); err != nil {
+		return "", err
+	}
+	return rc.readReply()
+}
+
+func (rc *redisConn) readReply() (string, error) {
+	line, err := rc.reader.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if len(line) == 0 {
+		return "", fmt.Errorf("redis: empty reply")
+	}
+
+	switch line[0] {
+	case '+', ':':
+		return line[1:], nil
+	case '-':
+		return "", fmt.Errorf("redis: %s", line[1:])
+	case '$':
+		length, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return "", fmt.Errorf("redis: invalid bulk length %q", line[1:])
+		}
+		if length < 0 {
+			return "", nil
+		}
+		data := make([]byte, length+2)
+		if _, err := io.ReadFull(rc.reader, data); err != nil {
+			return "", err
+		}
+		return string(data[:length]), nil
+	}
+	return "", fmt.Errorf("redis: unsupported reply type %q", line[0])
+}
@@ -0,0 +1,109 @@
+package health
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/blendlabs/go-assert"
+)
+
+// serveRedis is a minimal stand-in for a redis server that requires `password`
+// (if set) and reports `info` for `INFO replication`.
+func serveRedis(t *testing.T, password, info string) net.Listener {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				reader := bufio.NewReader(conn)
+				authenticated := len(password) == 0
+				for {
+					header, err := reader.ReadString('\n')
+					if err != nil {
+						return
+					}
+					var count int
+					fmt.Sscanf(header, "*%d", &count)
+					var args []string
+					for index := 0; index < count; index++ {
+						reader.ReadString('\n')
+						arg, _ := reader.ReadString('\n')
+						args = append(args, strings.TrimSpace(arg))
+					}
+
+					switch {
+					case args[0] == "AUTH":
+						if args[len(args)-1] != password {
+							conn.Write([]byte("-WRONGPASS invalid password\r\n"))
+							continue
+						}
+						authenticated = true
+						conn.Write([]byte("+OK\r\n"))
+					case !authenticated:
+						conn.Write([]byte("-NOAUTH Authentication required.\r\n"))
+					case args[0] == "PING":
+						conn.Write([]byte("+PONG\r\n"))
+					case args[0] == "INFO":
+						fmt.Fprintf(conn, "$%d\r\n%s\r\n", len(info), info)
+					}
+				}
+			}()
+		}
+	}()
+	return listener
+}
+
+func TestRedisProber(t *testing.T) {
+	assert := assert.New(t)
+
+	info := "# Replication\r\nrole:master\r\nconnected_slaves:2\r\nslave0:ip=10.0.0.2,port=6379,state=online,offset=100,lag=0\r\nslave1:ip=10.0.0.3,port=6379,state=online,offset=90,lag=7\r\n"
+	server := serveRedis(t, "secret", info)
+	defer server.Close()
+	addr := server.Addr().String()
+
+	result := probeHost(assert, HostConfig{URL: "redis://:secret@" + addr})
+	assert.Nil(result.Err)
+	assert.Len(result.Timings, 2)
+
+	result = probeHost(assert, HostConfig{URL: "redis://" + addr})
+	assert.NotNil(result.Err)
+	assert.Contains("NOAUTH", result.Err.Error())
+
+	result = probeHost(assert, HostConfig{URL: "redis://:secret@" + addr + "?role=primary"})
+	assert.Nil(result.Err)
+	assert.Equal("role: master", result.Details)
+
+	result = probeHost(assert, HostConfig{URL: "redis://:secret@" + addr + "?role=replica"})
+	assert.NotNil(result.Err)
+	assert.Equal(StatusDown, result.Status)
+
+	result = probeHost(assert, HostConfig{URL: "redis://:secret@" + addr + "?maxLag=10"})
+	assert.Nil(result.Err)
+	assert.Equal("role: master, lag: 7s", result.Details)
+
+	result = probeHost(assert, HostConfig{URL: "redis://:secret@" + addr + "?maxLag=5"})
+	assert.NotNil(result.Err)
+}
+
+func TestRedisProberReplica(t *testing.T) {
+	assert := assert.New(t)
+
+	prober := &RedisProber{role: "slave", maxLag: 5}
+	details, err := prober.checkReplication("role:slave\r\nmaster_link_status:up\r\nmaster_last_io_seconds_ago:2\r\n")
+	assert.Nil(err)
+	assert.Equal("role: slave, lag: 2s", details)
+
+	_, err = prober.checkReplication("role:slave\r\nmaster_link_status:down\r\nmaster_last_io_seconds_ago:-1\r\n")
+	assert.NotNil(err)
+}
@@ -0,0 +1,214 @@
+package health
+
+import (
+	"fmt"
+	"io/ioutil"
+	"math"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// procRoot is where the `/proc` filesystem is read from.
+var procRoot = "/proc"
+
+// NewSystemProber returns a new prober for a resource on the local machine,
+// for urls of the form:
+//
+//	system://disk[/mount/path]?warn=80&critical=90  (percent used)
+//	system://load?warn=4&critical=8                 (1 minute load average)
+//	system://memory?warn=80&critical=95             (percent used)
+//	system://fds?warn=80&critical=90                (percent of the system limit)
+func NewSystemProber(hostURL *url.URL, config *HostConfig) (Prober, error) {
+	prober := &SystemProber{resource: strings.ToLower(hostURL.Host), path: hostURL.Path}
+	switch prober.resource {
+	case "disk":
+		if len(prober.path) == 0 {
+			prober.path = "/"
+		}
+	case "load", "memory", "fds":
+	default:
+		return nil, fmt.Errorf("unknown system resource %q, expected one of disk, load, memory or fds", hostURL.Host)
+	}
+
+	var err error
+	query := hostURL.Query()
+	if prober.warn, err = parseThreshold(query.Get("warn")); err != nil {
+		return nil, err
+	}
+	if prober.critical, err = parseThreshold(query.Get("critical")); err != nil {
+		return nil, err
+	}
+	return prober, nil
+}
+
+// parseThreshold parses an optional threshold, returning NaN if it isn't set.
+func parseThreshold(value string) (float64, error) {
+	if len(value) == 0 {
+		return math.NaN(), nil
+	}
+	threshold, err := strconv.ParseFloat(strings.TrimSuffix(value, "%"), 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid threshold %q", value)
+	}
+	return threshold, nil
+}
+
+// SystemProber checks a resource on the machine `health` runs on against warn
+// and critical thresholds, which are exceeded when the value is above them.
+type SystemProber struct {
+	resource string
+	path     string
+	warn     float64
+	critical float64
+}
+
+// Probe reads the resource and compares its value to the thresholds.
+func (sp *SystemProber) Probe(timeout time.Duration) ProbeResult {
+	begin := time.Now()
+	var metrics []Metric
+	var err error
+	switch sp.resource {
+	case "disk":
+		metrics, err = diskMetrics(sp.path)
+	case "load":
+		metrics, err = loadMetrics()
+	case "memory":
+		metrics, err = memoryMetrics()
+	case "fds":
+		metrics, err = fdMetrics()
+	}
+	elapsed := time.Now().Sub(begin)
+	if err != nil {
+		return ProbeResult{Elapsed: elapsed, Status: StatusUnknown, Err: err}
+	}
+
+	// the first metric is the one the thresholds apply to.
+	result := NewProbeResult(elapsed, nil)
+	result.Metrics = metrics
+	value := metrics[0]
+	switch {
+	case value.Value > sp.critical:
+		result.Status = StatusDown
+		result.Err = fmt.Errorf("%s %s is above the critical threshold of %v", sp.resource, value, sp.critical)
+	case value.Value > sp.warn:
+		result.Status = StatusWarn
+	}
+	return result
+}
+
+// diskMetrics returns the percent used and the free space of the filesystem containing `path`.
+func diskMetrics(path string) ([]Metric, error) {
+	used, available, err := diskUsage(path)
+	if err != nil {
+		return nil, err
+	}
+	if used+available == 0 {
+		return nil, fmt.Errorf("disk %s reports no blocks", path)
+	}
+	return diskUsageMetrics(used, available), nil
+}
+
+// diskUsageMetrics returns the percent used and the free space from the bytes
+// used and available, computing the percent as `df` does so that space
+// reserved for root isn't counted as used.
+func diskUsageMetrics(used, available uint64) []Metric {
+	return []Metric{
+		{Name: "used", Value: round(100*float64(used)/float64(used+available), 1), Unit: "%"},
+		{Name: "free", Value: round(float64(available)/(1<<30), 1), Unit: "GB"},
+	}
+}
+
+// loadMetrics returns the 1, 5 and 15 minute load averages from `/proc/loadavg`.
+func loadMetrics() ([]Metric, error) {
+	contents, err := ioutil.ReadFile(filepath.Join(procRoot, "loadavg"))
+	if err != nil {
+		return nil, err
+	}
+	fields := strings.Fields(string(contents))
+	if len(fields) < 3 {
+		return nil, fmt.Errorf("invalid loadavg: %q", contents)
+	}
+	var metrics []Metric
+	for index, name := range []string{"load1", "load5", "load15"} {
+		value, err := strconv.ParseFloat(fields[index], 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid loadavg: %q", contents)
+		}
+		metrics = append(metrics, Metric{Name: name, Value: value})
+	}
+	return metrics, nil
+}
+
+// memoryMetrics returns the percent of memory in use from `/proc/meminfo`,
+// counting reclaimable memory as free, and the memory pressure stall average
+// from `/proc/pressure/memory` where the kernel provides it.
+func memoryMetrics() ([]Metric, error) {
+	contents, err := ioutil.ReadFile(filepath.Join(procRoot, "meminfo"))
+	if err != nil {
+		return nil, err
+	}
+	values := map[string]float64{}
+	for _, line := range strings.Split(string(contents), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		if value, err := strconv.ParseFloat(fields[1], 64); err == nil {
+			values[strings.TrimSuffix(fields[0], ":")] = value
+		}
+	}
+	total, available := values["MemTotal"], values["MemAvailable"]
+	if total == 0 {
+		return nil, fmt.Errorf("no MemTotal in meminfo")
+	}
+	if _, hasAvailable := values["MemAvailable"]; !hasAvailable {
+		return nil, fmt.Errorf("no MemAvailable in meminfo, which requires linux 3.14 or later")
+	}
+	metrics := []Metric{{Name: "used", Value: round(100*(total-available)/total, 1), Unit: "%"}}
+	if swapTotal := values["SwapTotal"]; swapTotal > 0 {
+		metrics = append(metrics, Metric{Name: "swap", Value: round(100*(swapTotal-values["SwapFree"])/swapTotal, 1), Unit: "%"})
+	}
+
+	// some avg10=0.00 avg60=0.00 avg300=0.00 total=0
+	if pressure, err := ioutil.ReadFile(filepath.Join(procRoot, "pressure", "memory")); err == nil {
+		for _, field := range strings.Fields(strings.SplitN(string(pressure), "\n", 2)[0]) {
+			if strings.HasPrefix(field, "avg10=") {
+				if value, err := strconv.ParseFloat(strings.TrimPrefix(field, "avg10="), 64); err == nil {
+					metrics = append(metrics, Metric{Name: "pressure", Value: value, Unit: "%"})
+				}
+			}
+		}
+	}
+	return metrics, nil
+}
+
+// fdMetrics returns the percent of the system's file descriptor limit in use,
+// and the number open, from `/proc/sys/fs/file-nr`.
+func fdMetrics() ([]Metric, error) {
+	contents, err := ioutil.ReadFile(filepath.Join(procRoot, "sys", "fs", "file-nr"))
+	if err != nil {
+		return nil, err
+	}
+	fields := strings.Fields(string(contents))
+	if len(fields) < 3 {
+		return nil, fmt.Errorf("invalid file-nr: %q", contents)
+	}
+	open, openErr := strconv.ParseFloat(fields[0], 64)
+	limit, limitErr := strconv.ParseFloat(fields[2], 64)
+	if openErr != nil || limitErr != nil || limit == 0 {
+		return nil, fmt.Errorf("invalid file-nr: %q", contents)
+	}
+	return []Metric{
+		{Name: "used", Value: round(100*open/limit, 2), Unit: "%"},
+		{Name: "open", Value: open},
+	}, nil
+}
+
+// round rounds a value to a number of decimal places.
+func round(value float64, places int) float64 {
+	scale := math.Pow(10, float64(places))
+	return math.Round(value*scale) / scale
+}
@@ -0,0 +1,115 @@
+package health
+
+import (
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/blendlabs/go-assert"
+)
+
+// withProcRoot points the system checks at a fake `/proc` with the given files.
+func withProcRoot(t *testing.T, files map[string]string, action func()) {
+	dir, err := ioutil.TempDir("", "health-proc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for name, contents := range files {
+		path := filepath.Join(dir, name)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := ioutil.WriteFile(path, []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	original := procRoot
+	procRoot = dir
+	defer func() { procRoot = original }()
+	action()
+}
+
+func TestSystemProber(t *testing.T) {
+	assert := assert.New(t)
+
+	files := map[string]string{
+		"loadavg":         "3.20 1.50 0.75 2/345 6789\n",
+		"meminfo":         "MemTotal:       16000000 kB\nMemFree:         1000000 kB\nMemAvailable:    4000000 kB\nSwapTotal:       2000000 kB\nSwapFree:        1500000 kB\n",
+		"pressure/memory": "some avg10=1.25 avg60=0.50 avg300=0.10 total=12345\nfull avg10=0.00 avg60=0.00 avg300=0.00 total=0\n",
+		"sys/fs/file-nr":  "9000\t0\t100000\n",
+	}
+	withProcRoot(t, files, func() {
+		result := probeHost(assert, HostConfig{URL: "system://load?warn=2&critical=4"})
+		assert.Equal(StatusWarn, result.Status)
+		assert.Nil(result.Err)
+		assert.Equal("load1=3.2 load5=1.5 load15=0.75", formatMetrics(result.Metrics))
+
+		result = probeHost(assert, HostConfig{URL: "system://load?warn=1&critical=3"})
+		assert.Equal(StatusDown, result.Status)
+		assert.Equal("load load1=3.2 is above the critical threshold of 3", result.Err.Error())
+
+		result = probeHost(assert, HostConfig{URL: "system://memory?warn=80&critical=95"})
+		assert.Equal(StatusUp, result.Status)
+		assert.Equal("used=75% swap=25% pressure=1.25%", formatMetrics(result.Metrics))
+
+		result = probeHost(assert, HostConfig{URL: "system://fds?warn=5%25&critical=50%25"})
+		assert.Equal(StatusWarn, result.Status)
+		assert.Equal("used=9% open=9000", formatMetrics(result.Metrics))
+	})
+
+	withProcRoot(t, map[string]string{}, func() {
+		result := probeHost(assert, HostConfig{URL: "system://load?warn=2"})
+		assert.Equal(StatusUnknown, result.Status)
+		assert.NotNil(result.Err)
+	})
+
+	withProcRoot(t, map[string]string{"meminfo": "MemTotal:       16000000 kB\nMemFree:         1000000 kB\n"}, func() {
+		result := probeHost(assert, HostConfig{URL: "system://memory?warn=80"})
+		assert.Equal(StatusUnknown, result.Status)
+		assert.NotNil(result.Err)
+	})
+}
+
+func TestDiskUsageMetrics(t *testing.T) {
+	assert := assert.New(t)
+
+	// a 100GB ext4 filesystem with 5% reserved for root and 50GB free, which df reports as 53% used.
+	const gb = 1 << 30
+	used, free, reserved := uint64(50*gb), uint64(50*gb), uint64(5*gb)
+	metrics := diskUsageMetrics(used, free-reserved)
+	assert.Equal("used=52.6% free=45GB", formatMetrics(metrics))
+}
+
+func TestSystemProberDisk(t *testing.T) {
+	assert := assert.New(t)
+
+	result := probeHost(assert, HostConfig{URL: "system://disk" + os.TempDir()})
+	assert.Nil(result.Err)
+	assert.Len(result.Metrics, 2)
+	assert.Equal("used", result.Metrics[0].Name)
+
+	result = probeHost(assert, HostConfig{URL: "system://disk?critical=-1"})
+	assert.Equal(StatusDown, result.Status)
+}
+
+func TestSystemProberValidation(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, rawURL := range []string{"system://cpu", "system://load?warn=high"} {
+		hostURL, err := url.Parse(rawURL)
+		assert.Nil(err)
+		_, err = NewProber(hostURL, &HostConfig{URL: rawURL})
+		assert.NotNil(err)
+	}
+}
+
+func formatMetrics(metrics []Metric) string {
+	var formatted []string
+	for _, metric := range metrics {
+		formatted = append(formatted, metric.String())
+	}
+	return strings.Join(formatted, " ")
+}
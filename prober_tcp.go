@@ -0,0 +1,52 @@
+package health
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"time"
+)
+
+// NewTCPProber returns a new tcp connect prober.
+func NewTCPProber(hostURL *url.URL, config *HostConfig) (Prober, error) {
+	if len(hostURL.Port()) == 0 {
+		return nil, fmt.Errorf("tcp host must include a port: %s", hostURL.String())
+	}
+	steps, err := compileTCPCheck(config.TCPCheck)
+	if err != nil {
+		return nil, err
+	}
+	return &TCPProber{addr: config.dialAddr(hostURL.Host), steps: steps}, nil
+}
+
+// TCPProber checks a host by timing a tcp connect to `host:port`, and then
+// running the host's `tcpCheck` send / expect steps if it has any.
+type TCPProber struct {
+	addr  string
+	steps []tcpCheckStep
+}
+
+// Probe dials the host and returns the connect time, or the connect and script
+// times if the host has a script.
+func (tp *TCPProber) Probe(timeout time.Duration) ProbeResult {
+	begin := time.Now()
+	conn, err := net.DialTimeout("tcp", tp.addr, timeout)
+	connected := time.Now()
+	if err != nil {
+		return NewProbeResult(connected.Sub(begin), err)
+	}
+	defer conn.Close()
+	if len(tp.steps) == 0 {
+		return NewProbeResult(connected.Sub(begin), nil)
+	}
+
+	conn.SetDeadline(begin.Add(timeout))
+	err = runTCPCheck(conn, tp.steps)
+	done := time.Now()
+	result := NewProbeResult(done.Sub(begin), err)
+	result.Timings = []Timing{
+		{Name: "connect", Elapsed: connected.Sub(begin)},
+		{Name: "script", Elapsed: done.Sub(connected)},
+	}
+	return result
+}
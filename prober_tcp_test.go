@@ -0,0 +1,125 @@
+package health
+
+import (
+	"net"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/blendlabs/go-assert"
+)
+
+func TestTCPProber(t *testing.T) {
+	assert := assert.New(t)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(err)
+	defer listener.Close()
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+
+	hostURL, err := url.Parse("tcp://" + listener.Addr().String())
+	assert.Nil(err)
+	prober, err := NewProber(hostURL, &HostConfig{URL: hostURL.String()})
+	assert.Nil(err)
+
+	result := prober.Probe(time.Second)
+	assert.Nil(result.Err)
+	assert.Equal(StatusUp, result.Status)
+
+	listener.Close()
+	result = prober.Probe(time.Second)
+	assert.NotNil(result.Err)
+	assert.Equal(StatusDown, result.Status)
+}
+
+func TestTCPProberRequiresPort(t *testing.T) {
+	assert := assert.New(t)
+
+	hostURL, err := url.Parse("tcp://localhost")
+	assert.Nil(err)
+	_, err = NewProber(hostURL, &HostConfig{URL: hostURL.String()})
+	assert.NotNil(err)
+}
+
+// serveSMTPBanner greets with an smtp banner and answers `QUIT`.
+func serveSMTPBanner(t *testing.T) net.Listener {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				conn.Write([]byte("220 mail.example.com ESMTP ready\r\n"))
+				buffer := make([]byte, 64)
+				read, _ := conn.Read(buffer)
+				if string(buffer[:read]) == "QUIT\r\n" {
+					conn.Write([]byte("221 2.0.0 Bye\r\n"))
+				}
+			}()
+		}
+	}()
+	return listener
+}
+
+func TestTCPProberScript(t *testing.T) {
+	assert := assert.New(t)
+
+	listener := serveSMTPBanner(t)
+	defer listener.Close()
+
+	hostURL, err := url.Parse("tcp://" + listener.Addr().String())
+	assert.Nil(err)
+	prober, err := NewProber(hostURL, &HostConfig{URL: hostURL.String(), TCPCheck: []TCPCheckStep{
+		{ExpectRegex: `^220 \S+ ESMTP`},
+		{SendHex: "51 55 49 54 0d 0a"},
+		{Expect: "221"},
+	}})
+	assert.Nil(err)
+
+	result := prober.Probe(time.Second)
+	assert.Nil(result.Err)
+	assert.Len(result.Timings, 2)
+
+	prober, err = NewProber(hostURL, &HostConfig{URL: hostURL.String(), TCPCheck: []TCPCheckStep{
+		{Expect: "220"},
+		{Send: "HELO health\r\n"},
+		{Expect: "250"},
+	}})
+	assert.Nil(err)
+
+	result = prober.Probe(time.Second)
+	assert.NotNil(result.Err)
+	assert.Equal(StatusDown, result.Status)
+	assert.Contains(`step 3 (expect "250")`, result.Err.Error())
+}
+
+func TestTCPProberScriptValidation(t *testing.T) {
+	assert := assert.New(t)
+
+	hostURL, err := url.Parse("tcp://localhost:25")
+	assert.Nil(err)
+
+	_, err = NewProber(hostURL, &HostConfig{URL: hostURL.String(), TCPCheck: []TCPCheckStep{{Send: "a", Expect: "b"}}})
+	assert.NotNil(err)
+	_, err = NewProber(hostURL, &HostConfig{URL: hostURL.String(), TCPCheck: []TCPCheckStep{{SendHex: "zz"}}})
+	assert.NotNil(err)
+	_, err = NewProber(hostURL, &HostConfig{URL: hostURL.String(), TCPCheck: []TCPCheckStep{{}}})
+	assert.NotNil(err)
+	_, err = NewProber(hostURL, &HostConfig{URL: hostURL.String(), TCPCheck: []TCPCheckStep{{ExpectRegex: "("}}})
+	assert.NotNil(err)
+}
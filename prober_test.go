@@ -0,0 +1,17 @@
+package health
+
+import (
+	"net/url"
+	"time"
+
+	"github.com/blendlabs/go-assert"
+)
+
+// probeHost creates the prober for a host config and probes it once.
+func probeHost(assert *assert.Assertions, config HostConfig) ProbeResult {
+	hostURL, err := url.Parse(config.URL)
+	assert.Nil(err)
+	prober, err := NewProber(hostURL, &config)
+	assert.Nil(err)
+	return prober.Probe(time.Second)
+}
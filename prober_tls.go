@@ -0,0 +1,102 @@
+package health
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultTLSPort is the port used for tls hosts that don't specify one.
+	DefaultTLSPort = "443"
+)
+
+// NewTLSProber returns a new tls certificate prober.
+func NewTLSProber(hostURL *url.URL, config *HostConfig) (Prober, error) {
+	if len(hostURL.Hostname()) == 0 {
+		return nil, fmt.Errorf("tls host must include a hostname: %s", hostURL.String())
+	}
+	port := hostURL.Port()
+	if len(port) == 0 {
+		port = DefaultTLSPort
+	}
+	return &TLSProber{
+		addr:        config.dialAddr(net.JoinHostPort(hostURL.Hostname(), port)),
+		serverName:  hostURL.Hostname(),
+		warningDays: config.GetCertWarningDays(),
+	}, nil
+}
+
+// TLSProber checks a host by completing a tls handshake and inspecting the
+// certificate chain the server presents.
+type TLSProber struct {
+	addr        string
+	serverName  string
+	warningDays int
+}
+
+// Probe performs the handshake and checks the certificates.
+func (tp *TLSProber) Probe(timeout time.Duration) ProbeResult {
+	dialer := &net.Dialer{Timeout: timeout}
+
+	begin := time.Now()
+	// verification is done by `CheckCertificates` so we can still report on
+	// invalid chains.
+	conn, err := tls.DialWithDialer(dialer, "tcp", tp.addr, &tls.Config{
+		ServerName:         tp.serverName,
+		InsecureSkipVerify: true,
+	})
+	elapsed := time.Now().Sub(begin)
+	if err != nil {
+		return NewProbeResult(elapsed, err)
+	}
+	state := conn.ConnectionState()
+	conn.Close()
+
+	result := CheckCertificates(state.PeerCertificates, tp.serverName, tp.warningDays)
+	result.Elapsed = elapsed
+	return result
+}
+
+// CheckCertificates validates a presented certificate chain and reports on the leaf.
+// The result is a warning if the leaf expires within `warningDays`.
+func CheckCertificates(certs []*x509.Certificate, serverName string, warningDays int) ProbeResult {
+	return checkCertificates(certs, serverName, warningDays, nil)
+}
+
+// checkCertificates validates a chain against `roots`, or the system roots if it is nil.
+func checkCertificates(certs []*x509.Certificate, serverName string, warningDays int, roots *x509.CertPool) ProbeResult {
+	if len(certs) == 0 {
+		return NewProbeResult(0, fmt.Errorf("no certificates presented"))
+	}
+	leaf := certs[0]
+
+	daysLeft := int(leaf.NotAfter.Sub(time.Now()).Hours() / 24)
+	details := fmt.Sprintf("cert: %dd left, issuer: %s, sans: %s", daysLeft, leaf.Issuer.CommonName, strings.Join(leaf.DNSNames, ","))
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+	_, err := leaf.Verify(x509.VerifyOptions{
+		DNSName:       serverName,
+		Intermediates: intermediates,
+		Roots:         roots,
+	})
+	if err != nil {
+		result := NewProbeResult(0, fmt.Errorf("invalid certificate chain: %v", err))
+		result.Details = details
+		return result
+	}
+
+	result := NewProbeResult(0, nil)
+	result.Details = details
+	if daysLeft < warningDays {
+		result.Status = StatusWarn
+	}
+	return result
+}
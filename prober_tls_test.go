@@ -0,0 +1,123 @@
+package health
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/blendlabs/go-assert"
+)
+
+func TestTLSProberUntrustedChain(t *testing.T) {
+	assert := assert.New(t)
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	serverURL, err := url.Parse(server.URL)
+	assert.Nil(err)
+	hostURL, err := url.Parse("tls://" + serverURL.Host)
+	assert.Nil(err)
+	prober, err := NewProber(hostURL, &HostConfig{URL: hostURL.String()})
+	assert.Nil(err)
+
+	result := prober.Probe(time.Second)
+	assert.Equal(StatusDown, result.Status)
+	assert.NotNil(result.Err)
+	assert.Contains("issuer:", result.Details)
+}
+
+func TestCheckCertificatesWithoutCertificates(t *testing.T) {
+	assert := assert.New(t)
+
+	result := CheckCertificates(nil, "example.com", DefaultCertWarningDays)
+	assert.Equal(StatusDown, result.Status)
+	assert.NotNil(result.Err)
+}
+
+// newTestChain returns a root pool and a leaf for `example.com` signed by it,
+// which expires after `validFor`.
+func newTestChain(t *testing.T, validFor time.Duration) (*x509.CertPool, *x509.Certificate) {
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "health test ca"},
+		NotBefore:             time.Now().Add(-48 * time.Hour),
+		NotAfter:              time.Now().Add(365 * 24 * time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca, err := x509.ParseCertificate(caDER)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	leafKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leafTemplate := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "example.com"},
+		DNSNames:     []string{"example.com"},
+		NotBefore:    time.Now().Add(-24 * time.Hour),
+		NotAfter:     time.Now().Add(validFor),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	leafDER, err := x509.CreateCertificate(rand.Reader, leafTemplate, ca, &leafKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, err := x509.ParseCertificate(leafDER)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca)
+	return roots, leaf
+}
+
+func TestCheckCertificatesExpiry(t *testing.T) {
+	assert := assert.New(t)
+
+	roots, leaf := newTestChain(t, 90*24*time.Hour)
+	result := checkCertificates([]*x509.Certificate{leaf}, "example.com", DefaultCertWarningDays, roots)
+	assert.Nil(result.Err)
+	assert.Equal(StatusUp, result.Status)
+	assert.Contains("issuer: health test ca", result.Details)
+	assert.Contains("sans: example.com", result.Details)
+
+	result = checkCertificates([]*x509.Certificate{leaf}, "other.example.com", DefaultCertWarningDays, roots)
+	assert.Equal(StatusDown, result.Status)
+
+	roots, leaf = newTestChain(t, 5*24*time.Hour)
+	result = checkCertificates([]*x509.Certificate{leaf}, "example.com", DefaultCertWarningDays, roots)
+	assert.Nil(result.Err)
+	assert.Equal(StatusWarn, result.Status)
+	result = checkCertificates([]*x509.Certificate{leaf}, "example.com", 3, roots)
+	assert.Equal(StatusUp, result.Status)
+
+	roots, leaf = newTestChain(t, -time.Hour)
+	result = checkCertificates([]*x509.Certificate{leaf}, "example.com", DefaultCertWarningDays, roots)
+	assert.Equal(StatusDown, result.Status)
+	assert.NotNil(result.Err)
+	assert.Contains("expired", result.Err.Error())
+}
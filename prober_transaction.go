@@ -0,0 +1,326 @@
+package health
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var variablePattern = regexp.MustCompile(`\$\{(\w+)\}`)
+
+// HTTPStep is one request in a multi-step http transaction. Its url is resolved
+// against the host url, and its url, headers and body can reference variables
+// extracted by earlier steps as `${name}`.
+type HTTPStep struct {
+	Name         string            `json:"name" yaml:"name"`
+	URL          string            `json:"url" yaml:"url"`
+	Method       string            `json:"method" yaml:"method"`
+	Headers      map[string]string `json:"headers" yaml:"headers"`
+	Body         string            `json:"body" yaml:"body"`
+	ExpectStatus string            `json:"expect_status" yaml:"expectStatus"`
+	Assertions   []BodyAssertion   `json:"assertions" yaml:"assertions"`
+	Extract      []Extraction      `json:"extract" yaml:"extract"`
+}
+
+// Extraction saves a value from a response into a variable. Exactly one of
+// `jsonPath`, `header` or `regex` should be set; a `regex` extracts its first
+// capture group, or the whole match if it has none.
+type Extraction struct {
+	Name     string `json:"name" yaml:"name"`
+	JSONPath string `json:"json_path" yaml:"jsonPath"`
+	Header   string `json:"header" yaml:"header"`
+	Regex    string `json:"regex" yaml:"regex"`
+
+	matches *regexp.Regexp
+}
+
+// Validate returns an error if the extraction is malformed.
+func (e Extraction) Validate() error {
+	if len(e.Name) == 0 {
+		return fmt.Errorf("extraction must have a `name`")
+	}
+	var set int
+	for _, value := range []string{e.JSONPath, e.Header, e.Regex} {
+		if len(value) > 0 {
+			set++
+		}
+	}
+	if set != 1 {
+		return fmt.Errorf("extraction %q must set exactly one of `jsonPath`, `header` or `regex`", e.Name)
+	}
+	if len(e.Regex) > 0 {
+		if _, err := regexp.Compile(e.Regex); err != nil {
+			return fmt.Errorf("invalid extraction regex %q: %v", e.Regex, err)
+		}
+	}
+	return nil
+}
+
+// Compile validates the extraction and returns a copy of it with its regex
+// compiled, so it isn't compiled on every probe.
+func (e Extraction) Compile() (Extraction, error) {
+	if err := e.Validate(); err != nil {
+		return e, err
+	}
+	if len(e.Regex) > 0 {
+		e.matches = regexp.MustCompile(e.Regex)
+	}
+	return e, nil
+}
+
+// CompileExtractions compiles a list of extractions, returning a copy of them.
+func CompileExtractions(extractions []Extraction) ([]Extraction, error) {
+	var compiled []Extraction
+	for _, extraction := range extractions {
+		extraction, err := extraction.Compile()
+		if err != nil {
+			return nil, err
+		}
+		compiled = append(compiled, extraction)
+	}
+	return compiled, nil
+}
+
+// Extract returns the extracted value from a response.
+func (e Extraction) Extract(res *http.Response, body []byte) (string, error) {
+	switch {
+	case len(e.Header) > 0:
+		value := res.Header.Get(e.Header)
+		if len(value) == 0 {
+			return "", fmt.Errorf("header %q not found for %q", e.Header, e.Name)
+		}
+		return value, nil
+	case len(e.Regex) > 0:
+		pattern := e.matches
+		if pattern == nil {
+			var err error
+			if pattern, err = regexp.Compile(e.Regex); err != nil {
+				return "", err
+			}
+		}
+		match := pattern.FindSubmatch(body)
+		if match == nil {
+			return "", fmt.Errorf("regex %q did not match for %q", e.Regex, e.Name)
+		}
+		if len(match) > 1 {
+			return string(match[1]), nil
+		}
+		return string(match[0]), nil
+	}
+	document, err := ParseJSON(body)
+	if err != nil {
+		return "", err
+	}
+	value, exists := JSONPath(document, e.JSONPath)
+	if !exists {
+		return "", fmt.Errorf("json path %q not found for %q", e.JSONPath, e.Name)
+	}
+	return FormatJSONValue(value), nil
+}
+
+// ExpandVariables replaces `${name}` references with their values.
+func ExpandVariables(value string, variables map[string]string) string {
+	return variablePattern.ReplaceAllStringFunc(value, func(reference string) string {
+		return variables[variablePattern.FindStringSubmatch(reference)[1]]
+	})
+}
+
+// NewTransactionProber returns a prober that runs the host's `steps` in order.
+// The host's `basicAuth` and `bearerToken` are sent with every step, and its
+// other checks (`assertions`, `metricAssertions`, `freshness`, `expectFinalURL`,
+// `checkCertificate` and `version`) are run against the last step's response.
+func NewTransactionProber(hostURL *url.URL, config *HostConfig) (Prober, error) {
+	if len(config.Steps) == 0 {
+		return nil, fmt.Errorf("transaction must have at least one step: %s", hostURL.Redacted())
+	}
+	final, err := newHTTPProber(hostURL, config)
+	if err != nil {
+		return nil, err
+	}
+	prober := &TransactionProber{
+		url:         hostURL,
+		headers:     config.Headers,
+		basicAuth:   config.BasicAuth,
+		bearerToken: config.BearerToken,
+		transport:   config.pinTransport(http.DefaultTransport.(*http.Transport).Clone()),
+		final:       final,
+	}
+
+	extracted := map[string]bool{}
+	for index, step := range config.Steps {
+		name := step.Name
+		if len(name) == 0 {
+			name = fmt.Sprintf("step %d", index+1)
+		}
+		if _, err := hostURL.Parse(step.URL); err != nil {
+			return nil, fmt.Errorf("%s: %v", name, err)
+		}
+		assertions, err := CompileBodyAssertions(step.Assertions)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", name, err)
+		}
+		step.Assertions = assertions
+		expectStatus := step.ExpectStatus
+		if len(expectStatus) == 0 {
+			expectStatus = config.GetExpectStatus()
+		}
+		codes, err := ParseStatusCodes(expectStatus)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", name, err)
+		}
+
+		references := step.URL + step.Body
+		for key, value := range step.Headers {
+			references += key + value
+		}
+		for _, reference := range variablePattern.FindAllStringSubmatch(references, -1) {
+			if !extracted[reference[1]] {
+				return nil, fmt.Errorf("%s: variable %q is not extracted by an earlier step", name, reference[1])
+			}
+		}
+		extractions, err := CompileExtractions(step.Extract)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", name, err)
+		}
+		step.Extract = extractions
+		for _, extraction := range step.Extract {
+			extracted[extraction.Name] = true
+		}
+
+		prober.steps = append(prober.steps, transactionStep{HTTPStep: step, name: name, expectStatus: codes})
+	}
+	// the last step's status has already been checked against its own `expectStatus`.
+	final.expectStatus = prober.steps[len(prober.steps)-1].expectStatus
+	return prober, nil
+}
+
+// TransactionProber checks a host by running a sequence of http requests that
+// share a cookie jar, like a user logging in and then using the site.
+type TransactionProber struct {
+	url         *url.URL
+	headers     map[string]string
+	basicAuth   *BasicAuth
+	bearerToken string
+	transport   *http.Transport
+	steps       []transactionStep
+	final       *HTTPProber
+}
+
+type transactionStep struct {
+	HTTPStep
+	name         string
+	expectStatus StatusCodes
+}
+
+// ReportsVersion returns if the host has a `version` extractor, which is run
+// against the last step's response.
+func (tp *TransactionProber) ReportsVersion() bool {
+	return tp.final.ReportsVersion()
+}
+
+// Probe runs each step in order, stopping at the first failure, and then runs
+// the host's checks against the last response. Each step is reported as a
+// timing and in the details, and the total as the elapsed time. The timeout
+// applies to the transaction as a whole.
+func (tp *TransactionProber) Probe(timeout time.Duration) ProbeResult {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	jar, _ := cookiejar.New(nil)
+	client := &http.Client{
+		Transport:     tp.transport,
+		Jar:           jar,
+		CheckRedirect: tp.final.checkRedirect,
+	}
+	variables := map[string]string{}
+
+	var res *http.Response
+	var body []byte
+	var err error
+	var timings []Timing
+	var stepDetails []string
+	begin := time.Now()
+	for _, step := range tp.steps {
+		stepBegin := time.Now()
+		res, body, err = tp.runStep(ctx, client, step, variables)
+		timing := Timing{Name: step.name, Elapsed: time.Now().Sub(stepBegin)}
+		timings = append(timings, timing)
+		stepDetails = append(stepDetails, fmt.Sprintf("%s: %s", timing.Name, FormatRoundedDuration(timing.Elapsed, time.Millisecond)))
+		if err != nil {
+			err = fmt.Errorf("%s: %v", step.name, err)
+			break
+		}
+	}
+	elapsed := time.Now().Sub(begin)
+
+	result := NewProbeResult(elapsed, err)
+	if err == nil {
+		result = tp.final.check(res, body, elapsed)
+	}
+	result.Timings = timings
+	if len(tp.steps) > 1 {
+		result.Details = strings.TrimSuffix(strings.Join(stepDetails, ", ")+", "+result.Details, ", ")
+	}
+	return result
+}
+
+// runStep issues a single step's request, checks the response and stores its
+// extractions. It returns the response and its body for the host's checks.
+func (tp *TransactionProber) runStep(ctx context.Context, client *http.Client, step transactionStep, variables map[string]string) (*http.Response, []byte, error) {
+	stepURL, err := tp.url.Parse(ExpandVariables(step.URL, variables))
+	if err != nil {
+		return nil, nil, err
+	}
+	method := "GET"
+	if len(step.Method) > 0 {
+		method = strings.ToUpper(step.Method)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, stepURL.String(), bytes.NewBufferString(ExpandVariables(step.Body, variables)))
+	if err != nil {
+		return nil, nil, err
+	}
+	for key, value := range tp.headers {
+		req.Header.Set(key, value)
+	}
+	if tp.basicAuth != nil {
+		req.SetBasicAuth(tp.basicAuth.Username, tp.basicAuth.Password)
+	}
+	if len(tp.bearerToken) > 0 {
+		req.Header.Set("Authorization", "Bearer "+tp.bearerToken)
+	}
+	for key, value := range step.Headers {
+		req.Header.Set(ExpandVariables(key, variables), ExpandVariables(value, variables))
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer res.Body.Close()
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if !step.expectStatus.Contains(res.StatusCode) {
+		return nil, nil, fmt.Errorf("unexpected status code %d returned from %s, expected %s", res.StatusCode, stepURL.Path, step.expectStatus)
+	}
+	if err := CheckBodyAssertions(step.Assertions, body); err != nil {
+		return nil, nil, err
+	}
+	for _, extraction := range step.Extract {
+		value, err := extraction.Extract(res, body)
+		if err != nil {
+			return nil, nil, fmt.Errorf("extraction failed: %v", err)
+		}
+		variables[extraction.Name] = value
+	}
+	return res, body, nil
+}
@@ -0,0 +1,150 @@
+package health
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/blendlabs/go-assert"
+)
+
+// serveShop requires a session cookie from `/login`, and an `X-CSRF` token
+// from `/account` to place an `/order`.
+func serveShop() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/login":
+			http.SetCookie(rw, &http.Cookie{Name: "session", Value: "s1"})
+			rw.Write([]byte(`{"user":{"id":42}}`))
+			return
+		}
+		if cookie, err := r.Cookie("session"); err != nil || cookie.Value != "s1" {
+			rw.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/users/42/account":
+			rw.Header().Set("X-CSRF", "t0k3n")
+			rw.Write([]byte(`<a href="/orders/1001">latest</a>`))
+		case "/orders/1001":
+			if r.Method != "POST" || r.Header.Get("X-CSRF") != "t0k3n" {
+				rw.WriteHeader(http.StatusForbidden)
+				return
+			}
+			rw.Write([]byte(`{"status":"ok"}`))
+		default:
+			rw.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func TestTransactionProber(t *testing.T) {
+	assert := assert.New(t)
+
+	server := serveShop()
+	defer server.Close()
+
+	steps := []HTTPStep{
+		{Name: "login", URL: "/login", Method: "POST", Extract: []Extraction{{Name: "user", JSONPath: "user.id"}}},
+		{Name: "account", URL: "/users/${user}/account", Extract: []Extraction{
+			{Name: "csrf", Header: "X-CSRF"},
+			{Name: "order", Regex: `/orders/(\d+)`},
+		}},
+		{Name: "order", URL: "/orders/${order}", Method: "POST", Headers: map[string]string{"X-CSRF": "${csrf}"}, Assertions: []BodyAssertion{{JSONPath: "status", Equals: "ok"}}},
+	}
+	result := probeHost(assert, HostConfig{URL: server.URL, Steps: steps})
+	assert.Nil(result.Err)
+	assert.Len(result.Timings, 3)
+	assert.Equal("account", result.Timings[1].Name)
+	assert.Contains("login: ", result.Details)
+	assert.Contains(", account: ", result.Details)
+	assert.Contains(", order: ", result.Details)
+
+	result = probeHost(assert, HostConfig{URL: server.URL, Steps: []HTTPStep{{URL: "/users/42/account"}}})
+	assert.NotNil(result.Err)
+	assert.Contains("step 1: unexpected status code 401", result.Err.Error())
+}
+
+func TestTransactionProberHostChecks(t *testing.T) {
+	assert := assert.New(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer abc123" {
+			rw.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		rw.Header().Set("X-Version", "1.4.0")
+		rw.Write([]byte(`{"path":"` + r.URL.Path + `"}`))
+	}))
+	defer server.Close()
+
+	config := HostConfig{
+		URL:         server.URL,
+		BearerToken: "abc123",
+		Steps:       []HTTPStep{{Name: "first", URL: "/first"}, {Name: "second", URL: "/second"}},
+		Assertions:  []BodyAssertion{{JSONPath: "path", Equals: "/second"}},
+		Version:     &Extraction{Header: "X-Version"},
+	}
+	result := probeHost(assert, config)
+	assert.Nil(result.Err)
+	assert.Equal("1.4.0", result.Version)
+
+	config.Assertions = []BodyAssertion{{JSONPath: "path", Equals: "/first"}}
+	result = probeHost(assert, config)
+	assert.NotNil(result.Err)
+	assert.Len(result.Timings, 2)
+}
+
+func TestTransactionProberFailingStep(t *testing.T) {
+	assert := assert.New(t)
+
+	server := serveShop()
+	defer server.Close()
+
+	result := probeHost(assert, HostConfig{URL: server.URL, Steps: []HTTPStep{
+		{Name: "login", URL: "/login"},
+		{Name: "account", URL: "/users/42/account", Extract: []Extraction{{Name: "missing", Header: "X-Missing"}}},
+		{Name: "never", URL: "/"},
+	}})
+	assert.NotNil(result.Err)
+	assert.Equal(StatusDown, result.Status)
+	assert.Contains("account: extraction failed", result.Err.Error())
+	assert.Len(result.Timings, 2)
+}
+
+func TestTransactionProberValidation(t *testing.T) {
+	assert := assert.New(t)
+
+	hostURL, err := url.Parse("http://localhost")
+	assert.Nil(err)
+
+	_, err = NewProber(hostURL, &HostConfig{URL: hostURL.String(), Steps: []HTTPStep{{URL: "/users/${user}"}}})
+	assert.NotNil(err)
+	_, err = NewProber(hostURL, &HostConfig{URL: hostURL.String(), Steps: []HTTPStep{{URL: "/", Extract: []Extraction{{Name: "a", Header: "A", Regex: "a"}}}}})
+	assert.NotNil(err)
+	_, err = NewProber(hostURL, &HostConfig{URL: hostURL.String(), Steps: []HTTPStep{{URL: "/", ExpectStatus: "ok"}}})
+	assert.NotNil(err)
+}
+
+func TestCompileExtractions(t *testing.T) {
+	assert := assert.New(t)
+
+	extractions := []Extraction{{Name: "build", Regex: `build (\d+)`}, {Name: "user", Header: "X-User"}}
+	compiled, err := CompileExtractions(extractions)
+	assert.Nil(err)
+	assert.NotNil(compiled[0].matches)
+	assert.Nil(extractions[0].matches, "the extractions passed in should not be modified")
+
+	value, err := compiled[0].Extract(&http.Response{}, []byte("ok, build 42"))
+	assert.Nil(err)
+	assert.Equal("42", value)
+
+	_, err = CompileExtractions([]Extraction{{Name: "build", Regex: "("}})
+	assert.NotNil(err)
+}
+
+func TestExpandVariables(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal("Bearer abc, $5 ${}", ExpandVariables("Bearer ${token}, $5 ${}", map[string]string{"token": "abc"}))
+}
@@ -0,0 +1,274 @@
+package health
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+	websocketOpContinuation = 0x0
+	websocketOpText         = 0x1
+	websocketOpBinary       = 0x2
+	websocketOpClose        = 0x8
+	websocketOpPing         = 0x9
+	websocketOpPong         = 0xa
+)
+
+// WebSocketConfig is the message exchange for a websocket host.
+type WebSocketConfig struct {
+	Send   string `json:"send" yaml:"send"`
+	Expect string `json:"expect" yaml:"expect"`
+}
+
+// NewWebSocketProber returns a new websocket prober for `ws://` and `wss://` urls.
+func NewWebSocketProber(hostURL *url.URL, config *HostConfig) (Prober, error) {
+	if len(hostURL.Hostname()) == 0 {
+		return nil, fmt.Errorf("websocket host must include a hostname: %s", hostURL.String())
+	}
+	useTLS := strings.EqualFold(hostURL.Scheme, "wss")
+	port := hostURL.Port()
+	if len(port) == 0 {
+		port = "80"
+		if useTLS {
+			port = "443"
+		}
+	}
+
+	prober := &WebSocketProber{
+		url:     hostURL,
+		addr:    config.dialAddr(net.JoinHostPort(hostURL.Hostname(), port)),
+		useTLS:  useTLS,
+		headers: config.Headers,
+	}
+	if config.WebSocket != nil && len(config.WebSocket.Send) > 0 {
+		prober.send = config.WebSocket.Send
+		expect := config.WebSocket.Expect
+		if len(expect) == 0 {
+			expect = "^" + regexp.QuoteMeta(prober.send) + "$"
+		}
+		pattern, err := regexp.Compile(expect)
+		if err != nil {
+			return nil, fmt.Errorf("invalid websocket `expect` regex %q: %v", expect, err)
+		}
+		prober.expect = pattern
+	}
+	return prober, nil
+}
+
+// WebSocketProber checks a host by completing the websocket upgrade handshake,
+// and optionally sending a message and waiting for a matching reply.
+type WebSocketProber struct {
+	url     *url.URL
+	addr    string
+	useTLS  bool
+	headers map[string]string
+	send    string
+	expect  *regexp.Regexp
+}
+
+// Probe performs the handshake and message exchange, recording each separately.
+func (wp *WebSocketProber) Probe(timeout time.Duration) ProbeResult {
+	dialer := &net.Dialer{Timeout: timeout}
+
+	begin := time.Now()
+	var conn net.Conn
+	var err error
+	if wp.useTLS {
+		conn, err = tls.DialWithDialer(dialer, "tcp", wp.addr, &tls.Config{ServerName: wp.url.Hostname()})
+	} else {
+		conn, err = dialer.Dial("tcp", wp.addr)
+	}
+	if err != nil {
+		return NewProbeResult(time.Now().Sub(begin), err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(begin.Add(timeout))
+
+	reader := bufio.NewReader(conn)
+	err = wp.handshake(conn, reader)
+	handshakeDone := time.Now()
+	timings := []Timing{{Name: "handshake", Elapsed: handshakeDone.Sub(begin)}}
+	if err != nil || len(wp.send) == 0 {
+		result := NewProbeResult(handshakeDone.Sub(begin), err)
+		result.Timings = timings
+		return result
+	}
+
+	err = writeWebSocketFrame(conn, websocketOpText, []byte(wp.send))
+	var reply []byte
+	if err == nil {
+		reply, err = readWebSocketMessage(conn, reader)
+	}
+	roundTripDone := time.Now()
+	timings = append(timings, Timing{Name: "roundtrip", Elapsed: roundTripDone.Sub(handshakeDone)})
+	if err == nil && !wp.expect.Match(reply) {
+		err = fmt.Errorf("websocket: reply %q does not match %q", reply, wp.expect.String())
+	}
+	if err == nil {
+		writeWebSocketFrame(conn, websocketOpClose, []byte{0x03, 0xe8}) // 1000, normal closure
+	}
+
+	result := NewProbeResult(roundTripDone.Sub(begin), err)
+	result.Timings = timings
+	return result
+}
+
+// handshake sends the upgrade request and validates the server's response.
+func (wp *WebSocketProber) handshake(conn net.Conn, reader *bufio.Reader) error {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	key := base64.StdEncoding.EncodeToString(nonce)
+
+	requestURL := *wp.url
+	requestURL.Scheme = "http"
+	if wp.useTLS {
+		requestURL.Scheme = "https"
+	}
+	req, err := http.NewRequest("GET", requestURL.String(), nil)
+	if err != nil {
+		return err
+	}
+	for key, value := range wp.headers {
+		req.Header.Set(key, value)
+	}
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Sec-WebSocket-Key", key)
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	if err := req.Write(conn); err != nil {
+		return err
+	}
+
+	res, err := http.ReadResponse(reader, req)
+	if err != nil {
+		return err
+	}
+	if res.StatusCode != http.StatusSwitchingProtocols {
+		return fmt.Errorf("websocket: upgrade failed with status code %d", res.StatusCode)
+	}
+	if !strings.EqualFold(res.Header.Get("Upgrade"), "websocket") {
+		return fmt.Errorf("websocket: upgrade failed, server did not switch to websocket")
+	}
+	if accept := res.Header.Get("Sec-WebSocket-Accept"); accept != WebSocketAccept(key) {
+		return fmt.Errorf("websocket: upgrade failed, invalid Sec-WebSocket-Accept %q", accept)
+	}
+	return nil
+}
+
+// WebSocketAccept returns the `Sec-WebSocket-Accept` value for a `Sec-WebSocket-Key`.
+func WebSocketAccept(key string) string {
+	digest := sha1.Sum([]byte(key + websocketGUID))
+	return base64.StdEncoding.EncodeToString(digest[:])
+}
+
+// writeWebSocketFrame writes a single masked client frame.
+func writeWebSocketFrame(writer io.Writer, opcode byte, payload []byte) error {
+	frame := []byte{0x80 | opcode}
+	switch length := len(payload); {
+	case length < 126:
+		frame = append(frame, 0x80|byte(length))
+	case length <= 0xffff:
+		frame = append(frame, 0x80|126, 0, 0)
+		binary.BigEndian.PutUint16(frame[2:], uint16(length))
+	default:
+		frame = append(frame, 0x80|127, 0, 0, 0, 0, 0, 0, 0, 0)
+		binary.BigEndian.PutUint64(frame[2:], uint64(length))
+	}
+
+	mask := make([]byte, 4)
+	if _, err := rand.Read(mask); err != nil {
+		return err
+	}
+	frame = append(frame, mask...)
+	for index, b := range payload {
+		frame = append(frame, b^mask[index%4])
+	}
+	_, err := writer.Write(frame)
+	return err
+}
+
+// readWebSocketMessage reads frames until it has a complete text or binary
+// message, answering pings along the way.
+func readWebSocketMessage(writer io.Writer, reader *bufio.Reader) ([]byte, error) {
+	var message []byte
+	for {
+		header := make([]byte, 2)
+		if _, err := io.ReadFull(reader, header); err != nil {
+			return nil, err
+		}
+		fin := header[0]&0x80 != 0
+		opcode := header[0] & 0x0f
+		masked := header[1]&0x80 != 0
+
+		length := uint64(header[1] & 0x7f)
+		switch length {
+		case 126:
+			extended := make([]byte, 2)
+			if _, err := io.ReadFull(reader, extended); err != nil {
+				return nil, err
+			}
+			length = uint64(binary.BigEndian.Uint16(extended))
+		case 127:
+			extended := make([]byte, 8)
+			if _, err := io.ReadFull(reader, extended); err != nil {
+				return nil, err
+			}
+			length = binary.BigEndian.Uint64(extended)
+		}
+		if length > 1<<24 {
+			return nil, fmt.Errorf("websocket: frame too large (%d bytes)", length)
+		}
+
+		var mask []byte
+		if masked {
+			mask = make([]byte, 4)
+			if _, err := io.ReadFull(reader, mask); err != nil {
+				return nil, err
+			}
+		}
+		payload := make([]byte, length)
+		if _, err := io.ReadFull(reader, payload); err != nil {
+			return nil, err
+		}
+		if masked {
+			for index := range payload {
+				payload[index] ^= mask[index%4]
+			}
+		}
+
+		switch opcode {
+		case websocketOpClose:
+			return nil, fmt.Errorf("websocket: server closed the connection")
+		case websocketOpPing:
+			if err := writeWebSocketFrame(writer, websocketOpPong, payload); err != nil {
+				return nil, err
+			}
+			continue
+		case websocketOpPong:
+			continue
+		case websocketOpText, websocketOpBinary, websocketOpContinuation:
+			message = append(message, payload...)
+		default:
+			return nil, fmt.Errorf("websocket: unsupported opcode %d", opcode)
+		}
+		if fin {
+			return message, nil
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package health
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/blendlabs/go-assert"
+)
+
+// serveWebSocket upgrades connections and replies to each message with `reply`
+// (or echoes it if `reply` is empty). Requests without the `X-Token` header are refused.
+func serveWebSocket(reply string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Token") != "abc" {
+			rw.WriteHeader(http.StatusForbidden)
+			return
+		}
+		conn, buf, err := rw.(http.Hijacker).Hijack()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		buf.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n")
+		buf.WriteString("Sec-WebSocket-Accept: " + WebSocketAccept(r.Header.Get("Sec-WebSocket-Key")) + "\r\n\r\n")
+		buf.Flush()
+
+		reader := bufio.NewReader(conn)
+		for {
+			message, err := readWebSocketMessage(conn, reader)
+			if err != nil {
+				return
+			}
+			if len(reply) > 0 {
+				message = []byte(reply)
+			}
+			// server frames are unmasked.
+			conn.Write(append([]byte{0x81, byte(len(message))}, message...))
+		}
+	}))
+}
+
+func TestWebSocketProber(t *testing.T) {
+	assert := assert.New(t)
+
+	server := serveWebSocket("")
+	defer server.Close()
+	socketURL := "ws" + strings.TrimPrefix(server.URL, "http") + "/socket"
+	headers := map[string]string{"X-Token": "abc"}
+
+	result := probeHost(assert, HostConfig{URL: socketURL, Headers: headers})
+	assert.Nil(result.Err)
+	assert.Len(result.Timings, 1)
+
+	result = probeHost(assert, HostConfig{URL: socketURL})
+	assert.NotNil(result.Err)
+	assert.Contains("403", result.Err.Error())
+
+	result = probeHost(assert, HostConfig{URL: socketURL, Headers: headers, WebSocket: &WebSocketConfig{Send: "ping"}})
+	assert.Nil(result.Err)
+	assert.Len(result.Timings, 2)
+	assert.Equal("roundtrip", result.Timings[1].Name)
+}
+
+func TestWebSocketProberExpect(t *testing.T) {
+	assert := assert.New(t)
+
+	server := serveWebSocket(`{"type":"pong"}`)
+	defer server.Close()
+	socketURL := "ws" + strings.TrimPrefix(server.URL, "http") + "/socket"
+	headers := map[string]string{"X-Token": "abc"}
+
+	result := probeHost(assert, HostConfig{URL: socketURL, Headers: headers, WebSocket: &WebSocketConfig{Send: "ping"}})
+	assert.NotNil(result.Err)
+	assert.Equal(StatusDown, result.Status)
+
+	result = probeHost(assert, HostConfig{URL: socketURL, Headers: headers, WebSocket: &WebSocketConfig{Send: `{"type":"ping"}`, Expect: `"type":\s*"pong"`}})
+	assert.Nil(result.Err)
+}
+
+func TestWebSocketAccept(t *testing.T) {
+	assert := assert.New(t)
+	// from RFC 6455, section 1.3
+	assert.Equal("s3pPLMBiTxaQ9kYGzzhZRbK+xOo=", WebSocketAccept("dGhlIHNhbXBsZSBub25jZQ=="))
+}
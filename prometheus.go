@@ -0,0 +1,264 @@
+package health
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// PrometheusSample is a single sample from a prometheus text format scrape.
+type PrometheusSample struct {
+	Name   string
+	Labels map[string]string
+	Value  float64
+}
+
+// String returns the sample's series as it appears in the text format, e.g. `queue_depth{queue="jobs"}`.
+func (ps PrometheusSample) String() string {
+	return formatSeries(ps.Name, ps.Labels)
+}
+
+// ParsePrometheusText parses the prometheus text exposition format, ignoring
+// comments, `# HELP` and `# TYPE` lines, and timestamps.
+func ParsePrometheusText(body []byte) ([]PrometheusSample, error) {
+	var samples []PrometheusSample
+	for number, line := range strings.Split(string(body), "\n") {
+		line = strings.TrimSpace(line)
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+		name, labels, rest, err := parseSeries(line)
+		if err != nil {
+			return nil, fmt.Errorf("invalid metrics line %d: %v", number+1, err)
+		}
+		fields := strings.Fields(rest)
+		if len(fields) == 0 {
+			return nil, fmt.Errorf("invalid metrics line %d: missing value", number+1)
+		}
+		value, err := parsePrometheusValue(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("invalid metrics line %d: %v", number+1, err)
+		}
+		samples = append(samples, PrometheusSample{Name: name, Labels: labels, Value: value})
+	}
+	return samples, nil
+}
+
+// MetricAssertion is a threshold on scraped prometheus samples, like
+// `queue_depth{queue="jobs"} < 1000` or `up == 1`.
+type MetricAssertion struct {
+	Name      string
+	Labels    map[string]string
+	Operator  string
+	Threshold float64
+}
+
+// ParseMetricAssertion parses a `series operator number` expression, where
+// the operator is one of `<`, `<=`, `>`, `>=`, `==` or `!=`.
+func ParseMetricAssertion(expression string) (*MetricAssertion, error) {
+	name, labels, rest, err := parseSeries(strings.TrimSpace(expression))
+	if err != nil {
+		return nil, fmt.Errorf("invalid metric assertion %q: %v", expression, err)
+	}
+	rest = strings.TrimSpace(rest)
+
+	var operator string
+	for _, candidate := range []string{"<=", ">=", "==", "!=", "<", ">"} {
+		if strings.HasPrefix(rest, candidate) {
+			operator = candidate
+			break
+		}
+	}
+	if len(operator) == 0 {
+		return nil, fmt.Errorf("invalid metric assertion %q: expected one of <, <=, >, >=, == or !=", expression)
+	}
+	threshold, err := parsePrometheusValue(strings.TrimSpace(rest[len(operator):]))
+	if err != nil {
+		return nil, fmt.Errorf("invalid metric assertion %q: %v", expression, err)
+	}
+	return &MetricAssertion{Name: name, Labels: labels, Operator: operator, Threshold: threshold}, nil
+}
+
+// String returns the assertion as it would be configured.
+func (ma MetricAssertion) String() string {
+	return fmt.Sprintf("%s %s %s", formatSeries(ma.Name, ma.Labels), ma.Operator, strconv.FormatFloat(ma.Threshold, 'f', -1, 64))
+}
+
+// Matches returns if a sample has the assertion's name and at least its labels.
+func (ma MetricAssertion) Matches(sample PrometheusSample) bool {
+	if sample.Name != ma.Name {
+		return false
+	}
+	for key, value := range ma.Labels {
+		if sample.Labels[key] != value {
+			return false
+		}
+	}
+	return true
+}
+
+// Compare returns if a value satisfies the assertion.
+func (ma MetricAssertion) Compare(value float64) bool {
+	switch ma.Operator {
+	case "<":
+		return value < ma.Threshold
+	case "<=":
+		return value <= ma.Threshold
+	case ">":
+		return value > ma.Threshold
+	case ">=":
+		return value >= ma.Threshold
+	case "==":
+		return value == ma.Threshold
+	}
+	return value != ma.Threshold
+}
+
+// Check runs the assertion against every matching sample, and returns the
+// matching samples as metrics. It is an error if no samples match.
+func (ma MetricAssertion) Check(samples []PrometheusSample) ([]Metric, error) {
+	var matched []PrometheusSample
+	for _, sample := range samples {
+		if ma.Matches(sample) {
+			matched = append(matched, sample)
+		}
+	}
+	if len(matched) == 0 {
+		return nil, fmt.Errorf("metric assertion failed: no samples for %s", formatSeries(ma.Name, ma.Labels))
+	}
+
+	var metrics []Metric
+	var err error
+	for _, sample := range matched {
+		name := formatSeries(ma.Name, ma.Labels)
+		if len(matched) > 1 {
+			name = sample.String()
+		}
+		metrics = append(metrics, Metric{Name: name, Value: sample.Value})
+		if err == nil && !ma.Compare(sample.Value) {
+			err = fmt.Errorf("metric assertion failed: expected %s, got %s", ma.String(), strconv.FormatFloat(sample.Value, 'f', -1, 64))
+		}
+	}
+	return metrics, err
+}
+
+// CheckMetricAssertions runs a list of assertions against a scrape, returning
+// the values they looked at and the first failure.
+func CheckMetricAssertions(assertions []*MetricAssertion, body []byte) ([]Metric, error) {
+	samples, err := ParsePrometheusText(body)
+	if err != nil {
+		return nil, err
+	}
+	var metrics []Metric
+	var firstErr error
+	for _, assertion := range assertions {
+		checked, err := assertion.Check(samples)
+		metrics = append(metrics, checked...)
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	return metrics, firstErr
+}
+
+// parseSeries parses a metric name and optional `{label="value",...}` set
+// from the start of text, returning the remaining text.
+func parseSeries(text string) (name string, labels map[string]string, rest string, err error) {
+	end := strings.IndexFunc(text, func(r rune) bool {
+		return !(r == '_' || r == ':' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9'))
+	})
+	if end < 0 {
+		end = len(text)
+	}
+	name, rest = text[:end], strings.TrimLeft(text[end:], " \t")
+	if len(name) == 0 || (name[0] >= '0' && name[0] <= '9') {
+		return "", nil, "", fmt.Errorf("invalid metric name in %q", text)
+	}
+	if !strings.HasPrefix(rest, "{") {
+		return name, nil, rest, nil
+	}
+
+	labels = map[string]string{}
+	rest = rest[1:]
+	for {
+		rest = strings.TrimLeft(rest, " \t,")
+		if strings.HasPrefix(rest, "}") {
+			return name, labels, rest[1:], nil
+		}
+		equals := strings.Index(rest, "=")
+		if equals < 0 {
+			return "", nil, "", fmt.Errorf("invalid labels in %q", text)
+		}
+		key := strings.TrimSpace(rest[:equals])
+		rest = strings.TrimLeft(rest[equals+1:], " \t")
+		if !strings.HasPrefix(rest, `"`) {
+			return "", nil, "", fmt.Errorf("unquoted label value for %q in %q", key, text)
+		}
+
+		var value strings.Builder
+		var escaped, closed bool
+		var index int
+		for index = 1; index < len(rest); index++ {
+			c := rest[index]
+			if escaped {
+				if c == 'n' {
+					c = '\n'
+				}
+				value.WriteByte(c)
+				escaped = false
+				continue
+			}
+			if c == '\\' {
+				escaped = true
+				continue
+			}
+			if c == '"' {
+				closed = true
+				break
+			}
+			value.WriteByte(c)
+		}
+		if !closed {
+			return "", nil, "", fmt.Errorf("unterminated label value for %q in %q", key, text)
+		}
+		labels[key] = value.String()
+		rest = rest[index+1:]
+	}
+}
+
+// parsePrometheusValue parses a sample value, including `+Inf`, `-Inf` and `NaN`.
+func parsePrometheusValue(value string) (float64, error) {
+	switch value {
+	case "+Inf", "Inf":
+		return math.Inf(1), nil
+	case "-Inf":
+		return math.Inf(-1), nil
+	case "NaN":
+		return math.NaN(), nil
+	}
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value %q", value)
+	}
+	return parsed, nil
+}
+
+// formatSeries formats a name and labels, with the labels sorted by key.
+func formatSeries(name string, labels map[string]string) string {
+	if len(labels) == 0 {
+		return name
+	}
+	var keys []string
+	for key := range labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var pairs []string
+	for _, key := range keys {
+		pairs = append(pairs, fmt.Sprintf("%s=%q", key, labels[key]))
+	}
+	return fmt.Sprintf("%s{%s}", name, strings.Join(pairs, ","))
+}
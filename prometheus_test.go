@@ -0,0 +1,75 @@
+package health
+
+import (
+	"math"
+	"testing"
+
+	"github.com/blendlabs/go-assert"
+)
+
+const prometheusText = `# HELP queue_depth Jobs waiting in each queue.
+# TYPE queue_depth gauge
+queue_depth{queue="jobs"} 12
+queue_depth{queue="mail",region="us-east-1"} 2500 1712345678000
+queue_depth{queue="say \"hi\"\\n"} 3
+up 1
+process_open_fds 3.5e+02
+latency_seconds_bucket{le="+Inf"} +Inf
+`
+
+func TestParsePrometheusText(t *testing.T) {
+	assert := assert.New(t)
+
+	samples, err := ParsePrometheusText([]byte(prometheusText))
+	assert.Nil(err)
+	assert.Len(samples, 6)
+	assert.Equal("queue_depth", samples[0].Name)
+	assert.Equal("jobs", samples[0].Labels["queue"])
+	assert.Equal(12.0, samples[0].Value)
+	assert.Equal(`queue_depth{queue="mail",region="us-east-1"}`, samples[1].String())
+	assert.Equal(`say "hi"\n`, samples[2].Labels["queue"])
+	assert.Equal(350.0, samples[4].Value)
+	assert.True(math.IsInf(samples[5].Value, 1))
+
+	_, err = ParsePrometheusText([]byte(`queue_depth{queue="jobs} 12`))
+	assert.NotNil(err)
+	_, err = ParsePrometheusText([]byte(`up`))
+	assert.NotNil(err)
+}
+
+func TestMetricAssertion(t *testing.T) {
+	assert := assert.New(t)
+
+	samples, err := ParsePrometheusText([]byte(prometheusText))
+	assert.Nil(err)
+
+	assertion, err := ParseMetricAssertion(`queue_depth{queue="jobs"} < 1000`)
+	assert.Nil(err)
+	assert.Equal(`queue_depth{queue="jobs"} < 1000`, assertion.String())
+	metrics, err := assertion.Check(samples)
+	assert.Nil(err)
+	assert.Len(metrics, 1)
+	assert.Equal(`queue_depth{queue="jobs"}=12`, metrics[0].String())
+
+	assertion, err = ParseMetricAssertion(`queue_depth <= 1000`)
+	assert.Nil(err)
+	metrics, err = assertion.Check(samples)
+	assert.NotNil(err)
+	assert.Equal(`metric assertion failed: expected queue_depth <= 1000, got 2500`, err.Error())
+	assert.Len(metrics, 3)
+
+	assertion, err = ParseMetricAssertion(`up==1`)
+	assert.Nil(err)
+	_, err = assertion.Check(samples)
+	assert.Nil(err)
+
+	assertion, err = ParseMetricAssertion(`missing != 0`)
+	assert.Nil(err)
+	_, err = assertion.Check(samples)
+	assert.NotNil(err)
+
+	_, err = ParseMetricAssertion(`up = 1`)
+	assert.NotNil(err)
+	_, err = ParseMetricAssertion(`up > lots`)
+	assert.NotNil(err)
+}
@@ -0,0 +1,171 @@
+package health
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/blendlabs/go-util"
+)
+
+const (
+	// DefaultResolveInterval is the default time between re-resolving a `resolveAll` host.
+	DefaultResolveInterval = time.Minute
+)
+
+// resolvableSchemes are the schemes whose probers dial the host's name, and
+// so can be pinned to each of the ips it resolves to.
+var resolvableSchemes = map[string]bool{
+	"http":       true,
+	"https":      true,
+	"tcp":        true,
+	"tls":        true,
+	"ws":         true,
+	"wss":        true,
+	"redis":      true,
+	"rediss":     true,
+	"postgres":   true,
+	"postgresql": true,
+	"grpc":       true,
+	"grpcs":      true,
+}
+
+// lookupIPAddr resolves a hostname to its A and AAAA records; tests replace it.
+var lookupIPAddr = net.DefaultResolver.LookupIPAddr
+
+// GetResolveInterval returns the time between re-resolving a `resolveAll` host or a default.
+func (hc HostConfig) GetResolveInterval() time.Duration {
+	if hc.ResolveInterval > 0 {
+		return hc.ResolveInterval
+	}
+	return DefaultResolveInterval
+}
+
+// dialAddr returns the address to dial for `addr`, which is the pinned ip in
+// place of the host's name if the config is pinned.
+func (hc HostConfig) dialAddr(addr string) string {
+	if len(hc.dialIP) == 0 {
+		return addr
+	}
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil || !strings.EqualFold(host, hc.dialHost) {
+		return addr
+	}
+	return net.JoinHostPort(hc.dialIP, port)
+}
+
+// pinTransport makes a transport dial the pinned ip for the host's name, if
+// the config is pinned. The request url is left alone, so the `Host` header
+// and tls server name are still the host's name.
+func (hc HostConfig) pinTransport(transport *http.Transport) *http.Transport {
+	if len(hc.dialIP) == 0 {
+		return transport
+	}
+	dialer := &net.Dialer{}
+	transport.Proxy = nil
+	transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return dialer.DialContext(ctx, network, hc.dialAddr(addr))
+	}
+	return transport
+}
+
+// validateResolveAll returns an error if a host can't be expanded into a check per ip.
+func validateResolveAll(hostURL *url.URL) error {
+	if !resolvableSchemes[strings.ToLower(hostURL.Scheme)] {
+		return fmt.Errorf("`resolveAll` is not supported for %s hosts: %s", hostURL.Scheme, hostURL.Redacted())
+	}
+	hostname := hostURL.Hostname()
+	if len(hostname) == 0 || net.ParseIP(hostname) != nil {
+		return fmt.Errorf("`resolveAll` requires a host name to resolve: %s", hostURL.Redacted())
+	}
+	return nil
+}
+
+// Resolve re-resolves the host's name once its resolve interval has passed,
+// adding a child check for each new ip and dropping the children for ips that
+// have gone away. Children that are kept keep their stats. A failed lookup
+// keeps the current children, which are shown as stale, and is retried on the
+// next call.
+func (h *Host) Resolve(now time.Time) error {
+	if h.config == nil || !h.config.ResolveAll {
+		return nil
+	}
+	if h.resolveErr == nil && !h.resolvedAt.IsZero() && now.Sub(h.resolvedAt) < h.config.GetResolveInterval() {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.timeout)
+	defer cancel()
+	addrs, err := lookupIPAddr(ctx, h.url.Hostname())
+	if err != nil {
+		h.resolveErr = err
+		return err
+	}
+	h.resolvedAt = now
+	h.resolveErr = nil
+
+	existing := map[string]*Host{}
+	for _, child := range h.children {
+		existing[child.ip] = child
+	}
+	var children []*Host
+	for _, addr := range addrs {
+		ip := addr.IP.String()
+		if child, hasChild := existing[ip]; hasChild {
+			if child != nil {
+				children = append(children, child)
+				existing[ip] = nil
+			}
+			continue
+		}
+		config := *h.config
+		config.ResolveAll = false
+		config.dialHost = h.url.Hostname()
+		config.dialIP = ip
+		child, err := NewHostFromConfig(&config, h.timeout, h.maxStats)
+		if err != nil {
+			return err
+		}
+		child.ip = ip
+		children = append(children, child)
+		existing[ip] = nil
+	}
+	sort.Slice(children, func(i, j int) bool {
+		return compareIPs(net.ParseIP(children[i].ip), net.ParseIP(children[j].ip)) < 0
+	})
+	h.children = children
+	return nil
+}
+
+// resolveStatus returns a note for the status line of a `ResolveAll` host whose
+// last lookup failed, saying how old its children are.
+func (h Host) resolveStatus() string {
+	if h.resolveErr == nil {
+		return ""
+	}
+	if h.resolvedAt.IsZero() {
+		return util.ColorYellow.Apply(" ips not resolved yet")
+	}
+	age := FormatRoundedDuration(time.Now().Sub(h.resolvedAt), time.Second)
+	return util.ColorYellow.Apply(fmt.Sprintf(" stale ips, resolved %s ago", age))
+}
+
+// compareIPs orders ipv4 addresses before ipv6 ones, and then by value.
+func compareIPs(a, b net.IP) int {
+	a4, b4 := a.To4(), b.To4()
+	switch {
+	case a4 != nil && b4 == nil:
+		return -1
+	case a4 == nil && b4 != nil:
+		return 1
+	case a4 != nil:
+		return bytes.Compare(a4, b4)
+	}
+	return bytes.Compare(a.To16(), b.To16())
+}
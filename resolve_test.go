@@ -0,0 +1,142 @@
+package health
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/blendlabs/go-assert"
+)
+
+func TestHostConfigDialAddr(t *testing.T) {
+	assert := assert.New(t)
+
+	config := HostConfig{}
+	assert.Equal("fooserver.com:443", config.dialAddr("fooserver.com:443"))
+
+	config = HostConfig{dialHost: "fooserver.com", dialIP: "10.0.0.2"}
+	assert.Equal("10.0.0.2:443", config.dialAddr("fooserver.com:443"))
+	assert.Equal("10.0.0.2:443", config.dialAddr("FooServer.com:443"))
+	assert.Equal("cdn.fooserver.com:443", config.dialAddr("cdn.fooserver.com:443"))
+
+	config = HostConfig{dialHost: "fooserver.com", dialIP: "2001:db8::1"}
+	assert.Equal("[2001:db8::1]:6379", config.dialAddr("fooserver.com:6379"))
+}
+
+func TestNewHostResolveAllValidation(t *testing.T) {
+	assert := assert.New(t)
+
+	_, err := NewHostFromConfig(&HostConfig{URL: "https://fooserver.com", ResolveAll: true}, time.Second, 2)
+	assert.Nil(err)
+	_, err = NewHostFromConfig(&HostConfig{URL: "http://10.0.0.1", ResolveAll: true}, time.Second, 2)
+	assert.NotNil(err)
+	_, err = NewHostFromConfig(&HostConfig{URL: "exec:///usr/lib/nagios/plugins/check_load", ResolveAll: true}, time.Second, 2)
+	assert.NotNil(err)
+}
+
+func TestChecksResolveAll(t *testing.T) {
+	assert := assert.New(t)
+
+	var hostHeaders []string
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		hostHeaders = append(hostHeaders, r.Host)
+	}))
+	defer server.Close()
+	_, port, err := net.SplitHostPort(server.Listener.Addr().String())
+	assert.Nil(err)
+
+	// the server only listens on 127.0.0.1, so 127.0.0.2 is a dead backend.
+	ips := []string{"127.0.0.2", "127.0.0.1"}
+	defer func(lookup func(context.Context, string) ([]net.IPAddr, error)) { lookupIPAddr = lookup }(lookupIPAddr)
+	lookupIPAddr = func(_ context.Context, host string) ([]net.IPAddr, error) {
+		assert.Equal("api.fooserver.test", host)
+		var addrs []net.IPAddr
+		for _, ip := range ips {
+			addrs = append(addrs, net.IPAddr{IP: net.ParseIP(ip)})
+		}
+		return addrs, nil
+	}
+
+	hostURL := (&url.URL{Scheme: "http", Host: net.JoinHostPort("api.fooserver.test", port), Path: "/health"}).String()
+	config := NewConfig()
+	config.Hosts = []HostConfig{{URL: hostURL, ResolveAll: true, ResolveInterval: time.Nanosecond}}
+	checks, err := NewChecksFromConfig(config)
+	assert.Nil(err)
+	checks.PingAll()
+
+	host := checks.Hosts()[0]
+	assert.Len(host.Children(), 2)
+	up, down := host.Children()[0], host.Children()[1]
+	assert.Equal(hostURL+" [127.0.0.1]", up.Name())
+	assert.True(up.IsUp())
+	assert.Equal(hostURL+" [127.0.0.2]", down.Name())
+	assert.False(down.IsUp())
+	assert.Equal([]string{"api.fooserver.test:" + port}, hostHeaders)
+	assert.True(checks.HasErrors())
+
+	buf := bytes.NewBuffer(nil)
+	assert.Nil(checks.WriteStatus(buf))
+	assert.Contains("  - 127.0.0.1", buf.String())
+	assert.Contains("  - 127.0.0.2", buf.String())
+	assert.Contains(hostURL+" [127.0.0.2]", buf.String())
+
+	ips = []string{"127.0.0.1"}
+	checks.PingAll()
+	assert.Len(host.Children(), 1)
+	assert.True(up == host.Children()[0], "the remaining child should keep its stats")
+	assert.Equal(2, up.stats.Len())
+}
+
+func TestFindVersionDriftResolveAll(t *testing.T) {
+	assert := assert.New(t)
+
+	parent := newGroupHost(assert, "http://api.fooserver.com", "api", "1.3.9")
+	for _, ip := range []string{"10.0.0.1", "10.0.0.2"} {
+		child := newGroupHost(assert, "http://api.fooserver.com", "api", "1.4.0")
+		child.ip = ip
+		parent.children = append(parent.children, child)
+	}
+	hosts := append([]*Host{parent}, parent.children...)
+	assert.Empty(FindVersionDrift(hosts), "the parent's aggregate version should not be compared")
+
+	parent.children[1].version = "1.3.9"
+	drift := FindVersionDrift(hosts)
+	assert.Len(drift, 1)
+	assert.Len(drift[0].Hosts, 2)
+}
+
+func TestHostResolveRetriesFailedLookups(t *testing.T) {
+	assert := assert.New(t)
+
+	var lookupErr error
+	defer func(lookup func(context.Context, string) ([]net.IPAddr, error)) { lookupIPAddr = lookup }(lookupIPAddr)
+	lookupIPAddr = func(_ context.Context, _ string) ([]net.IPAddr, error) {
+		if lookupErr != nil {
+			return nil, lookupErr
+		}
+		return []net.IPAddr{{IP: net.ParseIP("10.0.0.1")}}, nil
+	}
+
+	host, err := NewHostFromConfig(&HostConfig{URL: "http://api.fooserver.test", ResolveAll: true}, time.Second, 16)
+	assert.Nil(err)
+	now := time.Now()
+	assert.Nil(host.Resolve(now))
+	assert.Len(host.Children(), 1)
+	assert.Empty(host.resolveStatus())
+
+	lookupErr = errors.New("no such host")
+	assert.NotNil(host.Resolve(now.Add(2 * DefaultResolveInterval)))
+	assert.Len(host.Children(), 1)
+	assert.Contains("stale ips", host.resolveStatus())
+
+	// a failed lookup is retried without waiting for the resolve interval.
+	lookupErr = nil
+	assert.Nil(host.Resolve(now.Add(2*DefaultResolveInterval + time.Second)))
+	assert.Empty(host.resolveStatus())
+}
@@ -0,0 +1,19 @@
+//go:build linux || darwin
+// +build linux darwin
+
+package health
+
+import "syscall"
+
+// diskUsage returns the space used and the space available to unprivileged
+// users, in bytes, of the filesystem containing `path`. Blocks reserved for
+// root are in neither, as with `df`.
+func diskUsage(path string) (used, available uint64, err error) {
+	var stat syscall.Statfs_t
+	if err = syscall.Statfs(path, &stat); err != nil {
+		return
+	}
+	used = (stat.Blocks - stat.Bfree) * uint64(stat.Bsize)
+	available = stat.Bavail * uint64(stat.Bsize)
+	return
+}
@@ -0,0 +1,15 @@
+//go:build !linux && !darwin
+// +build !linux,!darwin
+
+package health
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// diskUsage is not supported on this platform.
+func diskUsage(path string) (used, available uint64, err error) {
+	err = fmt.Errorf("disk checks are not supported on %s", runtime.GOOS)
+	return
+}
@@ -0,0 +1,138 @@
+package health
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+)
+
+// TCPCheckStep is one step of a scripted tcp check. Exactly one of `send`,
+// `sendHex`, `expect`, `expectHex` or `expectRegex` should be set.
+type TCPCheckStep struct {
+	Send        string `json:"send" yaml:"send"`
+	SendHex     string `json:"send_hex" yaml:"sendHex"`
+	Expect      string `json:"expect" yaml:"expect"`
+	ExpectHex   string `json:"expect_hex" yaml:"expectHex"`
+	ExpectRegex string `json:"expect_regex" yaml:"expectRegex"`
+}
+
+// String returns a short description of the step for error messages.
+func (step TCPCheckStep) String() string {
+	switch {
+	case len(step.Send) > 0:
+		return fmt.Sprintf("send %q", step.Send)
+	case len(step.SendHex) > 0:
+		return fmt.Sprintf("send hex %s", step.SendHex)
+	case len(step.Expect) > 0:
+		return fmt.Sprintf("expect %q", step.Expect)
+	case len(step.ExpectHex) > 0:
+		return fmt.Sprintf("expect hex %s", step.ExpectHex)
+	case len(step.ExpectRegex) > 0:
+		return fmt.Sprintf("expect regex %q", step.ExpectRegex)
+	}
+	return "empty step"
+}
+
+// compile validates the step and returns the bytes to send, or the pattern to expect.
+func (step TCPCheckStep) compile() (send []byte, expect *regexp.Regexp, err error) {
+	var set int
+	for _, value := range []string{step.Send, step.SendHex, step.Expect, step.ExpectHex, step.ExpectRegex} {
+		if len(value) > 0 {
+			set++
+		}
+	}
+	if set != 1 {
+		return nil, nil, fmt.Errorf("tcp check step must set exactly one of `send`, `sendHex`, `expect`, `expectHex` or `expectRegex`")
+	}
+
+	switch {
+	case len(step.Send) > 0:
+		return []byte(step.Send), nil, nil
+	case len(step.SendHex) > 0:
+		send, err = decodeHex(step.SendHex)
+		return send, nil, err
+	case len(step.Expect) > 0:
+		return nil, regexp.MustCompile(regexp.QuoteMeta(step.Expect)), nil
+	case len(step.ExpectHex) > 0:
+		data, err := decodeHex(step.ExpectHex)
+		if err != nil {
+			return nil, nil, err
+		}
+		return nil, regexp.MustCompile(regexp.QuoteMeta(string(data))), nil
+	}
+	expect, err = regexp.Compile(step.ExpectRegex)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid tcp check `expectRegex` %q: %v", step.ExpectRegex, err)
+	}
+	return nil, expect, nil
+}
+
+// decodeHex decodes hex bytes, ignoring whitespace, e.g. `0d 0a`.
+func decodeHex(value string) ([]byte, error) {
+	data, err := hex.DecodeString(strings.Join(strings.Fields(value), ""))
+	if err != nil {
+		return nil, fmt.Errorf("invalid tcp check hex %q: %v", value, err)
+	}
+	return data, nil
+}
+
+// tcpCheckStep is a compiled `TCPCheckStep`.
+type tcpCheckStep struct {
+	description string
+	send        []byte
+	expect      *regexp.Regexp
+}
+
+// compileTCPCheck validates and compiles a tcp check script.
+func compileTCPCheck(steps []TCPCheckStep) ([]tcpCheckStep, error) {
+	var compiled []tcpCheckStep
+	for index, step := range steps {
+		send, expect, err := step.compile()
+		if err != nil {
+			return nil, fmt.Errorf("tcp check step %d: %v", index+1, err)
+		}
+		compiled = append(compiled, tcpCheckStep{description: step.String(), send: send, expect: expect})
+	}
+	return compiled, nil
+}
+
+// runTCPCheck runs a compiled script against a connection. Each expect reads
+// until its pattern matches, and the data up to the end of the match is consumed.
+// The connection's deadline bounds the whole script.
+func runTCPCheck(conn net.Conn, steps []tcpCheckStep) error {
+	var received []byte
+	chunk := make([]byte, 4096)
+	for index, step := range steps {
+		if step.send != nil {
+			if _, err := conn.Write(step.send); err != nil {
+				return fmt.Errorf("tcp check step %d (%s): %v", index+1, step.description, err)
+			}
+			continue
+		}
+
+		for {
+			if match := step.expect.FindIndex(received); match != nil {
+				received = received[match[1]:]
+				break
+			}
+			read, err := conn.Read(chunk)
+			received = append(received, chunk[:read]...)
+			if err != nil && step.expect.FindIndex(received) == nil {
+				return fmt.Errorf("tcp check step %d (%s) failed, got %q: %v", index+1, step.description, truncateBytes(received, 64), err)
+			}
+		}
+	}
+	return nil
+}
+
+// truncateBytes returns at most `length` bytes of data, trimming whitespace.
+func truncateBytes(data []byte, length int) []byte {
+	data = bytes.TrimSpace(data)
+	if len(data) > length {
+		return data[:length]
+	}
+	return data
+}
@@ -154,6 +154,15 @@ func FormatDuration(duration time.Duration) string {
 	return value
 }
 
+// FormatRoundedDuration rounds a duration to the given place and formats it,
+// as `0s` if it rounds to zero.
+func FormatRoundedDuration(duration, roundTo time.Duration) string {
+	if value := FormatDuration(RoundDuration(duration, roundTo)); len(value) > 0 {
+		return value
+	}
+	return "0s"
+}
+
 // RoundDuration rounds a duration to the given place.
 func RoundDuration(duration, roundTo time.Duration) time.Duration {
 	hours, minutes, seconds, milliseconds, microseconds := ExplodeDuration(duration)
@@ -53,6 +53,13 @@ func TestRoundDuration(t *testing.T) {
 	assert.Equal(time.Millisecond, RoundDuration(time.Millisecond+time.Microsecond, time.Millisecond))
 }
 
+func TestFormatRoundedDuration(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal("1s200ms", FormatRoundedDuration(1200*time.Millisecond+time.Microsecond, time.Millisecond))
+	assert.Equal("0s", FormatRoundedDuration(300*time.Microsecond, time.Millisecond))
+	assert.Equal("0s", FormatRoundedDuration(0, time.Second))
+}
+
 func TestFormatSparklines(t *testing.T) {
 	assert := assert.New(t)
 
@@ -0,0 +1,119 @@
+package health
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/blendlabs/go-util"
+)
+
+// GroupConfig is a named set of hosts that should be running the same
+// version, read from each host's responses by `version`.
+type GroupConfig struct {
+	Name    string      `json:"name" yaml:"name"`
+	Version *Extraction `json:"version" yaml:"version"`
+}
+
+// GroupDrift is a group whose hosts report different versions.
+type GroupDrift struct {
+	Group string
+	// Expected is the version most of the group reports.
+	Expected string
+	// Hosts maps the name of each host reporting a version to the version.
+	Hosts map[string]string
+}
+
+// Disagreeing returns the names of hosts that don't report the expected version, sorted.
+func (gd GroupDrift) Disagreeing() []string {
+	var names []string
+	for name, version := range gd.Hosts {
+		if version != gd.Expected {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// String summarizes the versions in the group, e.g. `api: 1.4.0 (3 hosts), 1.3.9 (1 host)`.
+func (gd GroupDrift) String() string {
+	counts := map[string]int{}
+	for _, version := range gd.Hosts {
+		counts[version]++
+	}
+	versions := sortedVersions(counts)
+
+	var summary []string
+	for _, version := range versions {
+		noun := "hosts"
+		if counts[version] == 1 {
+			noun = "host"
+		}
+		summary = append(summary, fmt.Sprintf("%s (%d %s)", version, counts[version], noun))
+	}
+	return fmt.Sprintf("%s: %s", gd.Group, strings.Join(summary, ", "))
+}
+
+// FindVersionDrift returns the groups whose hosts report more than one version.
+// Hosts that haven't reported a version, e.g. because they are down, are ignored,
+// as is a `ResolveAll` host with children, whose children are compared instead.
+func FindVersionDrift(hosts []*Host) []GroupDrift {
+	var groups []string
+	versions := map[string]map[string]string{}
+	for _, host := range hosts {
+		if len(host.group) == 0 || len(host.version) == 0 || len(host.children) > 0 {
+			continue
+		}
+		if _, hasGroup := versions[host.group]; !hasGroup {
+			versions[host.group] = map[string]string{}
+			groups = append(groups, host.group)
+		}
+		versions[host.group][host.Name()] = host.version
+	}
+
+	var drift []GroupDrift
+	for _, group := range groups {
+		counts := map[string]int{}
+		for _, version := range versions[group] {
+			counts[version]++
+		}
+		if len(counts) < 2 {
+			continue
+		}
+		drift = append(drift, GroupDrift{Group: group, Expected: sortedVersions(counts)[0], Hosts: versions[group]})
+	}
+	return drift
+}
+
+// sortedVersions returns versions from most to least common, breaking ties by name.
+func sortedVersions(counts map[string]int) []string {
+	var versions []string
+	for version := range counts {
+		versions = append(versions, version)
+	}
+	sort.Slice(versions, func(i, j int) bool {
+		if counts[versions[i]] != counts[versions[j]] {
+			return counts[versions[i]] > counts[versions[j]]
+		}
+		return versions[i] < versions[j]
+	})
+	return versions
+}
+
+// WriteVersionDrift writes a group's version summary and the hosts that disagree with the rest of it.
+func WriteVersionDrift(drift GroupDrift, hostWidth int, writer io.Writer) error {
+	buf := bytes.NewBuffer(nil)
+	buf.WriteString(drift.String())
+	buf.WriteString("\r\n")
+	for _, name := range drift.Disagreeing() {
+		buf.WriteString(util.ColorReset.Apply(util.String.FixedWidthLeftAligned(name, hostWidth+2)))
+		buf.WriteRune(rune(' '))
+		buf.WriteString(util.ColorYellow.Apply(drift.Hosts[name]))
+		buf.WriteString(fmt.Sprintf(" (expected %s)\r\n", drift.Expected))
+	}
+	_, err := writer.Write(buf.Bytes())
+	return err
+}
@@ -0,0 +1,106 @@
+package health
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/blendlabs/go-assert"
+)
+
+func newGroupHost(assert *assert.Assertions, rawURL, group, version string) *Host {
+	host, err := NewHostFromConfig(&HostConfig{URL: rawURL, Group: group}, time.Second, 16)
+	assert.Nil(err)
+	host.version = version
+	return host
+}
+
+func TestFindVersionDrift(t *testing.T) {
+	assert := assert.New(t)
+
+	hosts := []*Host{
+		newGroupHost(assert, "http://api-1", "api", "1.4.0"),
+		newGroupHost(assert, "http://api-2", "api", "1.3.9"),
+		newGroupHost(assert, "http://api-3", "api", "1.4.0"),
+		newGroupHost(assert, "http://api-4", "api", ""),
+		newGroupHost(assert, "http://web-1", "web", "2.0.0"),
+		newGroupHost(assert, "http://web-2", "web", "2.0.0"),
+		newGroupHost(assert, "http://other", "", "0.1.0"),
+	}
+
+	drift := FindVersionDrift(hosts)
+	assert.Len(drift, 1)
+	assert.Equal("api", drift[0].Group)
+	assert.Equal("1.4.0", drift[0].Expected)
+	assert.Equal([]string{"http://api-2"}, drift[0].Disagreeing())
+	assert.Equal("api: 1.4.0 (2 hosts), 1.3.9 (1 host)", drift[0].String())
+
+	buf := bytes.NewBuffer(nil)
+	assert.Nil(WriteVersionDrift(drift[0], 16, buf))
+	assert.Contains("http://api-2", buf.String())
+	assert.Contains("(expected 1.4.0)", buf.String())
+	assert.False(bytes.Contains(buf.Bytes(), []byte("http://api-1")))
+
+	hosts[1].version = "1.4.0"
+	assert.Empty(FindVersionDrift(hosts))
+}
+
+func TestChecksVersionDrift(t *testing.T) {
+	assert := assert.New(t)
+
+	version := "1.4.0"
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/canary" {
+			rw.Header().Set("X-Version", "1.5.0-rc1")
+		} else {
+			rw.Header().Set("X-Version", version)
+		}
+	}))
+	defer server.Close()
+
+	config := NewConfig()
+	config.Groups = []GroupConfig{{Name: "api", Version: &Extraction{Header: "X-Version"}}}
+	config.Hosts = []HostConfig{
+		{URL: server.URL + "/a", Group: "api"},
+		{URL: server.URL + "/b", Group: "api"},
+		{URL: server.URL + "/canary", Group: "api"},
+	}
+	checks, err := NewChecksFromConfig(config)
+	assert.Nil(err)
+	checks.PingAll()
+
+	buf := bytes.NewBuffer(nil)
+	assert.Nil(checks.WriteStatus(buf))
+	assert.Contains("Version Drift:", buf.String())
+	assert.Contains("api: 1.4.0 (2 hosts), 1.5.0-rc1 (1 host)", buf.String())
+
+	config.Hosts = []HostConfig{{URL: server.URL, Group: "missing"}}
+	_, err = NewChecksFromConfig(config)
+	assert.NotNil(err)
+}
+
+func TestChecksGroupValidation(t *testing.T) {
+	assert := assert.New(t)
+
+	config := NewConfig()
+	config.Groups = []GroupConfig{{Name: "api", Version: &Extraction{Header: "X-Version"}}, {Name: "workers"}}
+
+	config.Hosts = []HostConfig{{URL: "http://api-1.fooserver.com", Group: "api"}}
+	_, err := NewChecksFromConfig(config)
+	assert.Nil(err)
+	assert.Nil(config.Hosts[0].Version, "the group's version should not be written to the host config")
+
+	config.Hosts = []HostConfig{{URL: "grpc://api-1.fooserver.com:50051", Group: "api"}}
+	_, err = NewChecksFromConfig(config)
+	assert.NotNil(err)
+
+	config.Hosts = []HostConfig{{URL: "http://worker-1.fooserver.com", Group: "workers"}}
+	_, err = NewChecksFromConfig(config)
+	assert.NotNil(err)
+
+	config.Hosts = []HostConfig{{URL: "http://worker-1.fooserver.com", Group: "workers", Version: &Extraction{JSONPath: "version"}}}
+	_, err = NewChecksFromConfig(config)
+	assert.Nil(err)
+}